@@ -0,0 +1,204 @@
+// Package apnstest provides an in-process mock APNs server for testing code
+// that sends notifications with an apns.Client.
+//
+// The server speaks HTTP/2 over TLS like APNs, records every request it receives
+// and answers with canned responses configured per device token:
+//
+//	srv := apnstest.NewServer()
+//	defer srv.Close()
+//	srv.ExpectToken("stale-token").RespondUnregistered()
+//
+//	client, err := srv.NewClient()
+//	...
+//	_, err = client.Push(ctx, n) // *apns.Error with reason "Unregistered"
+package apnstest
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takimoto3/apns"
+	"github.com/takimoto3/appleapi-core"
+)
+
+// Token is the bearer token used by clients created with Server.NewClient.
+const Token = "apnstest-token"
+
+// Request is a request received by the Server.
+type Request struct {
+	// Method is the HTTP method, e.g. "POST".
+	Method string
+	// Path is the URL path, e.g. "/3/device/<token>".
+	Path string
+	// DeviceToken is the device token taken from the path.
+	DeviceToken string
+	// Proto is the protocol of the request, e.g. "HTTP/2.0".
+	Proto string
+	// Header holds the request headers.
+	Header http.Header
+	// Body is the request body.
+	Body []byte
+}
+
+// response is a canned response returned for a device token.
+type response struct {
+	status    int
+	apnsID    string
+	reason    string
+	timestamp int64
+}
+
+// Server is a mock APNs endpoint. By default it accepts every notification with
+// a 200 response, echoing the apns-id of the request or generating a new one.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]response
+	requests  []Request
+}
+
+// NewServer starts and returns a new Server. The caller should call Close when finished.
+func NewServer() *Server {
+	s := &Server{responses: make(map[string]response)}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	s.Server.EnableHTTP2 = true
+	s.Server.StartTLS()
+	return s
+}
+
+// Expectation configures the response returned for a single device token.
+type Expectation struct {
+	s     *Server
+	token string
+}
+
+// ExpectToken returns an Expectation for requests sent to the device token.
+// The configured response replaces any previous one for the same token.
+func (s *Server) ExpectToken(token string) *Expectation {
+	return &Expectation{s: s, token: token}
+}
+
+// RespondOK makes the server accept the notification and answer with apnsID.
+// If apnsID is empty, the apns-id of the request is echoed or a new one is generated.
+func (e *Expectation) RespondOK(apnsID string) {
+	e.set(response{status: http.StatusOK, apnsID: apnsID})
+}
+
+// RespondUnregistered makes the server reject the notification with a 410 and the
+// reason "Unregistered", timestamped with the current time.
+func (e *Expectation) RespondUnregistered() {
+	e.set(response{status: http.StatusGone, reason: "Unregistered", timestamp: time.Now().UnixMilli()})
+}
+
+// RespondError makes the server reject the notification with the given status and reason.
+func (e *Expectation) RespondError(status int, reason string) {
+	e.set(response{status: status, reason: reason})
+}
+
+func (e *Expectation) set(r response) {
+	e.s.mu.Lock()
+	e.s.responses[e.token] = r
+	e.s.mu.Unlock()
+}
+
+// Requests returns a copy of the requests received so far, in arrival order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the received requests and the configured responses.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.requests = nil
+	s.responses = make(map[string]response)
+	s.mu.Unlock()
+}
+
+// Option returns an apns.Option that sends the requests of a client to the
+// server and makes the client trust the server's certificate. The client keeps
+// its transport, so that its other options still apply. Apply it with
+// apns.Client.Configure to a client created with apns.NewClient,
+// apns.NewClientWithToken or apns.NewClientWithCert.
+func (s *Server) Option() apns.Option {
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+	opts := []apns.Option{apns.WithHost(s.URL), apns.WithRootCAs(roots)}
+	return func(cli *apns.Client) error {
+		for _, opt := range opts {
+			if err := opt(cli); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// NewClient returns a token-based apns.Client wired to the server.
+func (s *Server) NewClient(opts ...appleapi.Option) (*apns.Client, error) {
+	client, err := apns.NewClient(appleapi.DefaultHTTPClientInitializer(), staticToken(Token), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Configure(s.Option()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	token := strings.TrimPrefix(r.URL.Path, apns.Path)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		DeviceToken: token,
+		Proto:       r.Proto,
+		Header:      r.Header.Clone(),
+		Body:        body,
+	})
+	res, ok := s.responses[token]
+	s.mu.Unlock()
+	if !ok {
+		res = response{status: http.StatusOK}
+	}
+
+	apnsID := res.apnsID
+	if apnsID == "" {
+		apnsID = r.Header.Get("apns-id")
+	}
+	if apnsID == "" {
+		apnsID = uuid.NewString()
+	}
+	w.Header().Set("apns-id", apnsID)
+	w.Header().Set("apns-unique-id", uuid.NewString())
+
+	if res.status == http.StatusOK {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(res.status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp,omitempty"`
+	}{res.reason, res.timestamp})
+}
+
+// staticToken is a token.Provider that always returns the same token.
+type staticToken string
+
+func (t staticToken) GetToken(time.Time) (string, error) {
+	return string(t), nil
+}
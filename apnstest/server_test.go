@@ -0,0 +1,131 @@
+package apnstest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns"
+	"github.com/takimoto3/apns/apnstest"
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func newNotification(token string) *apns.Notification {
+	return &apns.Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: token,
+		Type:        notification.Alert,
+		Payload:     &apns.Payload{APS: payload.APS{Alert: "hello"}},
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := apnstest.NewServer()
+	defer srv.Close()
+
+	const apnsID = "123e4567-e89b-12d3-a456-4266554400a0"
+	srv.ExpectToken("good-token").RespondOK(apnsID)
+	srv.ExpectToken("stale-token").RespondUnregistered()
+	srv.ExpectToken("bad-token").RespondError(http.StatusBadRequest, "BadDeviceToken")
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	ctx := context.Background()
+
+	res, err := client.Push(ctx, newNotification("good-token"))
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if res.APNsID != apnsID {
+		t.Errorf("Expected APNsID %s, got %s", apnsID, res.APNsID)
+	}
+
+	_, err = client.Push(ctx, newNotification("stale-token"))
+	var apnsErr *apns.Error
+	if !errors.As(err, &apnsErr) {
+		t.Fatalf("Expected *apns.Error, got %v", err)
+	}
+	if apnsErr.StatusCode != http.StatusGone || apnsErr.Reason != "Unregistered" || apnsErr.Timestamp == 0 {
+		t.Errorf("Unexpected error: %+v", apnsErr)
+	}
+
+	_, err = client.Push(ctx, newNotification("bad-token"))
+	if !errors.As(err, &apnsErr) || apnsErr.StatusCode != http.StatusBadRequest || apnsErr.Reason != "BadDeviceToken" {
+		t.Errorf("Expected BadDeviceToken error, got %v", err)
+	}
+
+	n := newNotification("other-token")
+	n.APNsID = "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
+	res, err = client.Push(ctx, n)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if res.APNsID != n.APNsID {
+		t.Errorf("Expected echoed APNsID %s, got %s", n.APNsID, res.APNsID)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 4 {
+		t.Fatalf("Expected 4 requests, got %d", len(reqs))
+	}
+	first := reqs[0]
+	if first.Proto != "HTTP/2.0" {
+		t.Errorf("Expected HTTP/2.0, got %s", first.Proto)
+	}
+	if first.Method != http.MethodPost || first.Path != apns.Path+"good-token" || first.DeviceToken != "good-token" {
+		t.Errorf("Unexpected request: %s %s (%s)", first.Method, first.Path, first.DeviceToken)
+	}
+	if got := first.Header.Get("Authorization"); got != "Bearer "+apnstest.Token {
+		t.Errorf("Expected bearer token, got %q", got)
+	}
+	if got := first.Header.Get("apns-topic"); got != "com.example.app" {
+		t.Errorf("Expected apns-topic com.example.app, got %q", got)
+	}
+	if got := string(first.Body); got != `{"aps":{"alert":"hello"}}` {
+		t.Errorf("Unexpected body %s", got)
+	}
+
+	srv.Reset()
+	if len(srv.Requests()) != 0 {
+		t.Error("Expected no requests after Reset")
+	}
+	if _, err := client.Push(ctx, newNotification("stale-token")); err != nil {
+		t.Errorf("Expected default OK response after Reset, got %v", err)
+	}
+}
+
+func TestServer_Option(t *testing.T) {
+	srv := apnstest.NewServer()
+	defer srv.Close()
+
+	client, err := apns.NewClientWithToken(staticProvider{})
+	if err != nil {
+		t.Fatalf("NewClientWithToken failed: %v", err)
+	}
+	// The server's option keeps the client's transport, so that transport
+	// options apply whatever the order.
+	if err := client.Configure(apns.WithMaxIdleConns(10), srv.Option(), apns.WithMaxConnsPerHost(1)); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	if _, err := client.PushMulti(context.Background(), newNotification(""), []string{"t1", "t2", "t3"}); err != nil {
+		t.Fatalf("PushMulti failed: %v", err)
+	}
+	requests := srv.Requests()
+	if got := len(requests); got != 3 {
+		t.Fatalf("Expected 3 requests, got %d", got)
+	}
+	if requests[0].Proto != "HTTP/2.0" {
+		t.Errorf("Expected HTTP/2, got %q", requests[0].Proto)
+	}
+}
+
+type staticProvider struct{}
+
+func (staticProvider) GetToken(_ time.Time) (string, error) {
+	return "token", nil
+}
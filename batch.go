@@ -0,0 +1,388 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// BatchItem pairs a notification with the device token it is sent to in PushBatch.
+type BatchItem struct {
+	// Token is the device token. It overrides Notification.DeviceToken.
+	Token string
+	// Notification is the notification to send. It is not modified.
+	Notification *Notification
+}
+
+// validateBatch checks the inputs of a batch operation before any notification
+// is validated, marshaled or sent. It reports every problem it finds, joined
+// into a single error, except for an empty token list which is reported alone.
+func validateBatch(tokens []string, limit int, notifications ...*Notification) error {
+	if len(tokens) == 0 {
+		return errors.New("token list is empty")
+	}
+
+	var errs []error
+	if len(tokens) > limit {
+		batches := (len(tokens) + limit - 1) / limit
+		errs = append(errs, fmt.Errorf("token limit exceeded: got %d tokens, maximum allowed is %d; use PushChunked or split into %d batches", len(tokens), limit, batches))
+	}
+	seen := make(map[string]int, len(tokens))
+	for i, token := range tokens {
+		if token == "" {
+			errs = append(errs, fmt.Errorf("token at index %d is empty", i))
+			continue
+		}
+		if j, ok := seen[token]; ok {
+			errs = append(errs, fmt.Errorf("duplicate token %q at index %d and %d", token, j, i))
+			continue
+		}
+		seen[token] = i
+	}
+	for i, n := range notifications {
+		if n != nil {
+			continue
+		}
+		if len(notifications) == 1 {
+			errs = append(errs, errors.New("notification is nil"))
+		} else {
+			errs = append(errs, fmt.Errorf("notification at index %d is nil", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tokenFailure reports whether err concerns only the device token it was sent
+// to, e.g. Unregistered, so that a batch goes on with its other tokens. Errors
+// that Classify reports as Delete are token failures, and so is
+// DeviceTokenNotForTopic, which is Permanent since the token is not to be
+// deleted but is still specific to it; validation, authentication and transport
+// errors affect the whole batch.
+func tokenFailure(err error) bool {
+	if Classify(err) == Delete {
+		return true
+	}
+	var apnsErr *Error
+	return errors.As(err, &apnsErr) && apnsErr.Reason == "DeviceTokenNotForTopic"
+}
+
+// dedupeTokens returns the tokens without duplicates, keeping the first
+// occurrence of each, and a Deduplicated response for every duplicate dropped.
+func dedupeTokens(tokens []string) ([]string, []*Response) {
+	var deduped []*Response
+	unique := make([]string, 0, len(tokens))
+	seen := make(map[string]struct{}, len(tokens))
+	for _, token := range tokens {
+		if _, ok := seen[token]; ok {
+			deduped = append(deduped, &Response{DeviceToken: token, Deduplicated: true})
+			continue
+		}
+		seen[token] = struct{}{}
+		unique = append(unique, token)
+	}
+	return unique, deduped
+}
+
+// dedupeItems returns the items without duplicates, keeping the first
+// occurrence of each, and a Deduplicated response for every duplicate dropped.
+// An item is a duplicate if an earlier item has the same token and either the
+// same notification or the same non-empty collapse ID.
+func dedupeItems(items []BatchItem) ([]BatchItem, []*Response) {
+	type sameNotification struct {
+		token string
+		n     *Notification
+	}
+	type sameCollapseID struct {
+		token      string
+		collapseID string
+	}
+	var deduped []*Response
+	unique := make([]BatchItem, 0, len(items))
+	notifications := make(map[sameNotification]struct{}, len(items))
+	collapseIDs := make(map[sameCollapseID]struct{})
+	for _, item := range items {
+		byNotification := sameNotification{item.Token, item.Notification}
+		var byCollapseID sameCollapseID
+		if item.Notification != nil && item.Notification.CollapseID != "" {
+			byCollapseID = sameCollapseID{item.Token, item.Notification.CollapseID}
+		}
+		_, dup := notifications[byNotification]
+		if !dup && byCollapseID.collapseID != "" {
+			_, dup = collapseIDs[byCollapseID]
+		}
+		if dup {
+			deduped = append(deduped, &Response{DeviceToken: item.Token, Deduplicated: true})
+			continue
+		}
+		notifications[byNotification] = struct{}{}
+		if byCollapseID.collapseID != "" {
+			collapseIDs[byCollapseID] = struct{}{}
+		}
+		unique = append(unique, item)
+	}
+	return unique, deduped
+}
+
+// ChunkResult is the outcome of one chunk sent by PushChunked.
+type ChunkResult struct {
+	// Index is the position of the chunk, starting at 0.
+	Index int
+	// Start and End are the bounds of the chunk in the token list, tokens[Start:End],
+	// after any duplicates have been dropped because of the client's DedupeTokens.
+	Start, End int
+	// Responses holds the successful responses of the chunk.
+	Responses []*Response
+	// Failures maps the device tokens of the chunk that failed to their errors.
+	Failures map[string]error
+	// Duration is the time it took to send the chunk.
+	Duration time.Duration
+}
+
+// ChunkedResult is the outcome of PushChunked, broken down by chunk.
+type ChunkedResult struct {
+	// Chunks holds the chunks that were sent, in order.
+	Chunks []ChunkResult
+	// Deduplicated holds a Deduplicated response for every duplicate token that
+	// was dropped before chunking because the client's DedupeTokens is set.
+	Deduplicated []*Response
+}
+
+// Responses returns the successful responses of all chunks, in chunk order,
+// followed by the Deduplicated responses.
+func (r *ChunkedResult) Responses() []*Response {
+	var responses []*Response
+	for _, c := range r.Chunks {
+		responses = append(responses, c.Responses...)
+	}
+	return append(responses, r.Deduplicated...)
+}
+
+// Failures returns the failures of all chunks, keyed by device token.
+func (r *ChunkedResult) Failures() map[string]error {
+	failures := make(map[string]error)
+	for _, c := range r.Chunks {
+		for token, err := range c.Failures {
+			failures[token] = err
+		}
+	}
+	return failures
+}
+
+// PushChunked sends the same push notification to any number of device tokens by
+// splitting them into chunks of at most chunkSize tokens and sending each chunk
+// with PushMulti, one after another. If chunkSize is not positive or exceeds the
+// client's TokenLimits, TokenLimits is used.
+//
+// The result holds the responses, failures and timing of each chunk that was sent.
+// If any token failed, a `*MultiError` holding the failures of all chunks is
+// returned along with the result. If a chunk fails as a whole (e.g. the
+// notification is invalid or the credentials are rejected), the remaining chunks
+// are not sent and that error is returned instead; the result then holds the
+// chunks sent before it. A failure that concerns a single device token, such as
+// Unregistered, never fails a chunk as a whole, even for its first token.
+func (cli *Client) PushChunked(ctx context.Context, n *Notification, tokens []string, chunkSize int) (*ChunkedResult, error) {
+	cli.freeze()
+	if chunkSize <= 0 || chunkSize > cli.TokenLimits {
+		chunkSize = cli.TokenLimits
+	}
+	var deduped []*Response
+	if cli.DedupeTokens {
+		tokens, deduped = dedupeTokens(tokens)
+	}
+	if err := validateBatch(tokens, len(tokens), n); err != nil {
+		return nil, err
+	}
+
+	ctx = cli.withRetryBudget(ctx)
+
+	result := &ChunkedResult{
+		Chunks:       make([]ChunkResult, 0, (len(tokens)+chunkSize-1)/chunkSize),
+		Deduplicated: deduped,
+	}
+	for start := 0; start < len(tokens); start += chunkSize {
+		end := min(start+chunkSize, len(tokens))
+		began := time.Now()
+		responses, err := cli.PushMulti(ctx, n, tokens[start:end])
+		chunk := ChunkResult{
+			Index:     len(result.Chunks),
+			Start:     start,
+			End:       end,
+			Responses: responses,
+			Duration:  time.Since(began),
+		}
+		var multiErr *MultiError
+		switch {
+		case err == nil:
+		case errors.As(err, &multiErr):
+			chunk.Failures = multiErr.Failures
+		default:
+			return result, fmt.Errorf("chunk starting at token %d failed: %w", start, err)
+		}
+		result.Chunks = append(result.Chunks, chunk)
+	}
+
+	if failures := result.Failures(); len(failures) > 0 {
+		return result, &MultiError{Failures: failures}
+	}
+	return result, nil
+}
+
+// PushBatch sends a different notification to each device token concurrently.
+// Each item is transformed, validated and marshaled independently, so an
+// invalid item fails on its own without affecting the others. Items are
+// prepared by at most MarshalConcurrency goroutines and each is sent as soon as
+// it is ready. Items that share the same *Notification are marshaled only once,
+// unless the client has Transformers.
+//
+// Like PushMulti, it returns the successful responses and a `*MultiError` keyed
+// by device token that holds all failures, including validation failures.
+func (cli *Client) PushBatch(ctx context.Context, items []BatchItem) ([]*Response, error) {
+	cli.freeze()
+	var deduped []*Response
+	if cli.DedupeTokens {
+		items, deduped = dedupeItems(items)
+	}
+	tokens := make([]string, len(items))
+	notifications := make([]*Notification, len(items))
+	for i, item := range items {
+		tokens[i] = item.Token
+		notifications[i] = item.Notification
+	}
+	if err := validateBatch(tokens, cli.TokenLimits, notifications...); err != nil {
+		return nil, err
+	}
+	ctx = cli.withRetryBudget(ctx)
+
+	type result struct {
+		Token string
+		Resp  *Response
+		Err   error
+	}
+	results := make(chan result, len(items))
+	var wg sync.WaitGroup
+
+	workers := cli.MarshalConcurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	for p := range cli.prepareBatch(ctx, items, workers) {
+		if p.err != nil {
+			results <- result{Token: p.token, Err: p.err}
+			continue
+		}
+		wg.Add(1)
+		go func(p preparedItem) {
+			defer wg.Done()
+			response, err := cli.sendPaced(ctx, p.n, p.body)
+			results <- result{Token: p.token, Resp: response, Err: err}
+		}(p)
+	}
+	wg.Wait()
+	close(results)
+
+	successes := make([]*Response, 0, len(items))
+	failures := make(map[string]error)
+	for res := range results {
+		if res.Err != nil {
+			failures[res.Token] = res.Err
+		} else {
+			response := res.Resp
+			response.DeviceToken = res.Token
+			successes = append(successes, response)
+		}
+	}
+	successes = append(successes, deduped...)
+
+	if len(failures) > 0 {
+		return successes, &MultiError{Failures: failures}
+	}
+	return successes, nil
+}
+
+// preparedItem is a batch item that has been validated and marshaled, or the
+// error that prevented it.
+type preparedItem struct {
+	token string
+	n     *Notification
+	body  []byte
+	err   error
+}
+
+// preparedNotification holds the result of preparing a notification shared by
+// several batch items.
+type preparedNotification struct {
+	once sync.Once
+	n    *Notification
+	body []byte
+	err  error
+}
+
+// prepareBatch validates and marshals the items with at most workers goroutines
+// and delivers them on the returned channel, in no particular order, as they
+// become ready. The channel is closed once all items have been delivered.
+func (cli *Client) prepareBatch(ctx context.Context, items []BatchItem, workers int) <-chan preparedItem {
+	out := make(chan preparedItem, len(items))
+	indices := make(chan int)
+
+	// Transformers may depend on the device token, so notifications shared by
+	// several items are only prepared once without them.
+	var mu sync.Mutex
+	var shared map[*Notification]*preparedNotification
+	if len(cli.Transformers) == 0 {
+		shared = make(map[*Notification]*preparedNotification)
+	}
+	prepare := func(item BatchItem) preparedItem {
+		if err := ctx.Err(); err != nil {
+			return preparedItem{token: item.Token, err: err}
+		}
+		if shared == nil {
+			c := *item.Notification
+			c.DeviceToken = item.Token
+			n, body, err := cli.prepare(&c)
+			return preparedItem{token: item.Token, n: n, body: body, err: err}
+		}
+
+		mu.Lock()
+		entry, ok := shared[item.Notification]
+		if !ok {
+			entry = &preparedNotification{}
+			shared[item.Notification] = entry
+		}
+		mu.Unlock()
+		entry.once.Do(func() {
+			c := *item.Notification
+			c.DeviceToken = item.Token
+			entry.n, entry.body, entry.err = cli.prepare(&c)
+		})
+		if entry.err != nil {
+			return preparedItem{token: item.Token, err: entry.err}
+		}
+		c := *entry.n
+		c.DeviceToken = item.Token
+		return preparedItem{token: item.Token, n: &c, body: entry.body}
+	}
+
+	var wg sync.WaitGroup
+	for range min(workers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				out <- prepare(items[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range items {
+			indices <- i
+		}
+		close(indices)
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,618 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestClient_PushMulti_InputValidation(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		t.Errorf("unexpected request to %s", r.URL.Path)
+		return nil, context.Canceled
+	})
+	client := newTestClient(t, transport)
+	client.TokenLimits = 3
+
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	testCases := map[string]struct {
+		notification *Notification
+		tokens       []string
+		wantErrs     []string
+	}{
+		"Empty": {
+			notification: n,
+			tokens:       nil,
+			wantErrs:     []string{"token list is empty"},
+		},
+		"Oversized": {
+			notification: n,
+			tokens:       []string{"t1", "t2", "t3", "t4"},
+			wantErrs:     []string{"token limit exceeded: got 4 tokens, maximum allowed is 3; use PushChunked or split into 2 batches"},
+		},
+		"Nil notification": {
+			notification: nil,
+			tokens:       []string{"t1"},
+			wantErrs:     []string{"notification is nil"},
+		},
+		"Empty and duplicate tokens": {
+			notification: n,
+			tokens:       []string{"t1", "", "t1"},
+			wantErrs:     []string{"token at index 1 is empty", `duplicate token "t1" at index 0 and 2`},
+		},
+		"Aggregated": {
+			notification: nil,
+			tokens:       []string{"t1", "t2", "t3", "t1"},
+			wantErrs: []string{
+				"token limit exceeded: got 4 tokens, maximum allowed is 3",
+				`duplicate token "t1" at index 0 and 3`,
+				"notification is nil",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			responses, err := client.PushMulti(context.Background(), tc.notification, tc.tokens)
+			if err == nil {
+				t.Fatal("Expected an error, but got nil")
+			}
+			if responses != nil {
+				t.Errorf("Expected no responses, got %d", len(responses))
+			}
+			for _, want := range tc.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+				}
+			}
+		})
+	}
+	if got := hits.Load(); got != 0 {
+		t.Errorf("Expected no requests to be sent, got %d", got)
+	}
+}
+
+func TestClient_PushMulti_FailureConsistency(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		switch path.Base(r.URL.Path) {
+		case "token-fail":
+			return newResponse(http.StatusGone, `{"reason":"Unregistered"}`), nil
+		case "token-topic":
+			return newResponse(http.StatusBadRequest, `{"reason":"DeviceTokenNotForTopic"}`), nil
+		case "token-down":
+			return newResponse(http.StatusInternalServerError, `{"reason":"InternalServerError"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	client.DedupeTokens = true
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	// A token failure is reported the same way wherever the token is; only a
+	// failure of the whole batch on the first token stops the others.
+	testCases := map[string]struct {
+		tokens        []string
+		wantReason    string
+		wantSuccesses []string
+		wantMultiErr  bool
+		wantFailure   string
+		wantHits      int32
+	}{
+		"First token fails": {
+			tokens:        []string{"token-fail", "token-1", "token-2"},
+			wantReason:    "Unregistered",
+			wantSuccesses: []string{"token-1", "token-2"},
+			wantMultiErr:  true,
+			wantFailure:   "token-fail",
+			wantHits:      3,
+		},
+		"Later token fails": {
+			tokens:        []string{"token-1", "token-fail", "token-2"},
+			wantReason:    "Unregistered",
+			wantSuccesses: []string{"token-1", "token-2"},
+			wantMultiErr:  true,
+			wantFailure:   "token-fail",
+			wantHits:      3,
+		},
+		"First token not for the topic": {
+			tokens:        []string{"token-topic", "token-1", "token-2"},
+			wantReason:    "DeviceTokenNotForTopic",
+			wantSuccesses: []string{"token-1", "token-2"},
+			wantMultiErr:  true,
+			wantFailure:   "token-topic",
+			wantHits:      3,
+		},
+		"First token fails the batch": {
+			tokens:        []string{"token-down", "token-1", "token-2", "token-1"},
+			wantReason:    "InternalServerError",
+			wantSuccesses: []string{"token-1"}, // the dropped duplicate
+			wantMultiErr:  false,
+			wantHits:      1,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			hits.Store(0)
+			responses, err := client.PushMulti(context.Background(), n, tc.tokens)
+
+			var apnsErr *Error
+			if !errors.As(err, &apnsErr) || apnsErr.Reason != tc.wantReason {
+				t.Fatalf("Expected an *Error with reason %s, got %v", tc.wantReason, err)
+			}
+			var multiErr *MultiError
+			if got := errors.As(err, &multiErr); got != tc.wantMultiErr {
+				t.Errorf("errors.As(err, *MultiError) = %v, want %v", got, tc.wantMultiErr)
+			}
+			if multiErr != nil && (multiErr.Len() != 1 || multiErr.Failures[tc.wantFailure] == nil) {
+				t.Errorf("Expected a single failure for %s, got %v", tc.wantFailure, multiErr.Failures)
+			}
+
+			if responses == nil {
+				t.Fatal("Expected a non-nil slice of successful responses")
+			}
+			got := make([]string, 0, len(responses))
+			for _, resp := range responses {
+				if resp.StatusCode != http.StatusOK && !resp.Deduplicated {
+					t.Errorf("Response for %q has status %d among the successes", resp.DeviceToken, resp.StatusCode)
+				}
+				got = append(got, resp.DeviceToken)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tc.wantSuccesses) {
+				t.Errorf("Successful tokens = %v, want %v", got, tc.wantSuccesses)
+			}
+			if got := hits.Load(); got != tc.wantHits {
+				t.Errorf("Expected %d requests, got %d", tc.wantHits, got)
+			}
+		})
+	}
+}
+
+func TestClient_PushChunked(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		if strings.HasSuffix(r.URL.Path, "/token-42") {
+			return newResponse(http.StatusBadRequest, `{"reason":"BadDeviceToken"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	tokens := make([]string, 250)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token-%d", i)
+	}
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	if _, err := client.PushMulti(context.Background(), n, tokens); err == nil ||
+		!strings.Contains(err.Error(), "token limit exceeded: got 250 tokens, maximum allowed is 100; use PushChunked or split into 3 batches") {
+		t.Fatalf("Expected an enriched token limit error, got %v", err)
+	}
+
+	result, err := client.PushChunked(context.Background(), n, tokens, 100)
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected a *MultiError, got %v", err)
+	}
+	if len(multiErr.Failures) != 1 || multiErr.Failures["token-42"] == nil {
+		t.Errorf("Expected a single failure for token-42, got %v", multiErr.Failures)
+	}
+	if got := len(result.Responses()); got != 249 {
+		t.Errorf("Expected 249 successful responses, got %d", got)
+	}
+
+	wantChunks := []struct {
+		start, end, successes, failures int
+	}{
+		{0, 100, 99, 1},
+		{100, 200, 100, 0},
+		{200, 250, 50, 0},
+	}
+	if len(result.Chunks) != len(wantChunks) {
+		t.Fatalf("Expected %d chunks, got %d", len(wantChunks), len(result.Chunks))
+	}
+	for i, want := range wantChunks {
+		c := result.Chunks[i]
+		if c.Index != i || c.Start != want.start || c.End != want.end {
+			t.Errorf("chunk %d: got {Index:%d Start:%d End:%d}, want {Index:%d Start:%d End:%d}", i, c.Index, c.Start, c.End, i, want.start, want.end)
+		}
+		if len(c.Responses) != want.successes || len(c.Failures) != want.failures {
+			t.Errorf("chunk %d: got %d responses and %d failures, want %d and %d", i, len(c.Responses), len(c.Failures), want.successes, want.failures)
+		}
+		if c.Duration <= 0 {
+			t.Errorf("chunk %d: expected a positive duration, got %v", i, c.Duration)
+		}
+	}
+	if got := hits.Load(); got != 250 {
+		t.Errorf("Expected 250 requests, got %d", got)
+	}
+}
+
+func TestClient_PushChunked_FirstTokenOfChunkFails(t *testing.T) {
+	var hits atomic.Int32
+	var reason atomic.Value
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		if path.Base(r.URL.Path) == "token-100" {
+			status := http.StatusGone
+			if reason.Load() == "InvalidProviderToken" {
+				status = http.StatusForbidden
+			}
+			return newResponse(status, `{"reason":"`+reason.Load().(string)+`"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	tokens := make([]string, 250)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token-%d", i)
+	}
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	// A failure of a single device token is recorded and the chunks go on.
+	reason.Store("Unregistered")
+	result, err := client.PushChunked(context.Background(), n, tokens, 100)
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected a *MultiError, got %v", err)
+	}
+	if !errors.Is(multiErr.Failures["token-100"], ErrUnregistered) || multiErr.Len() != 1 {
+		t.Errorf("Expected a single Unregistered failure for token-100, got %v", multiErr.Failures)
+	}
+	if len(result.Chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(result.Chunks))
+	}
+	if c := result.Chunks[1]; len(c.Responses) != 99 || c.Failures["token-100"] == nil {
+		t.Errorf("chunk 1: got %d responses and failures %v, want 99 responses and token-100 failed", len(c.Responses), c.Failures)
+	}
+	if got := len(result.Responses()); got != 249 {
+		t.Errorf("Expected 249 successful responses, got %d", got)
+	}
+	if got := hits.Load(); got != 250 {
+		t.Errorf("Expected 250 requests, got %d", got)
+	}
+
+	// A failure that affects the whole batch stops the remaining chunks.
+	reason.Store("InvalidProviderToken")
+	hits.Store(0)
+	result, err = client.PushChunked(context.Background(), n, tokens, 100)
+	var apnsErr *Error
+	if !errors.As(err, &apnsErr) || apnsErr.Reason != "InvalidProviderToken" || errors.As(err, &multiErr) {
+		t.Fatalf("Expected a chunk error with reason InvalidProviderToken, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "chunk starting at token 100 failed") {
+		t.Errorf("Expected the failed chunk in the error, got %v", err)
+	}
+	if len(result.Chunks) != 1 {
+		t.Errorf("Expected 1 chunk before the failure, got %d", len(result.Chunks))
+	}
+	if got := hits.Load(); got != 101 {
+		t.Errorf("Expected 101 requests, got %d", got)
+	}
+}
+
+func TestClient_PushBatch(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		token := path.Base(r.URL.Path)
+		mu.Lock()
+		bodies[token] = string(b) + " " + r.Header.Get("apns-collapse-id")
+		mu.Unlock()
+		if token == "bad-token" {
+			return newResponse(http.StatusBadRequest, `{"reason":"BadDeviceToken"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	newNotification := func(alert, collapseID string) *Notification {
+		return &Notification{
+			BundleID:   "com.example.app",
+			Type:       notification.Alert,
+			CollapseID: collapseID,
+			Payload:    &Payload{APS: payload.APS{Alert: alert}},
+		}
+	}
+	items := []BatchItem{
+		{Token: "token-a", Notification: newNotification("hello a", "ca")},
+		{Token: "token-b", Notification: newNotification("hello b", "cb")},
+		{Token: "bad-token", Notification: newNotification("hello bad", "")},
+		{Token: "invalid-item", Notification: &Notification{BundleID: "com.example.app", Type: notification.Alert}},
+	}
+
+	responses, err := client.PushBatch(context.Background(), items)
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected a *MultiError, got %v", err)
+	}
+	if len(multiErr.Failures) != 2 {
+		t.Errorf("Expected 2 failures, got %v", multiErr.Failures)
+	}
+	var apnsErr *Error
+	if !errors.As(multiErr.Failures["bad-token"], &apnsErr) || apnsErr.Reason != "BadDeviceToken" {
+		t.Errorf("Expected BadDeviceToken for bad-token, got %v", multiErr.Failures["bad-token"])
+	}
+	if err := multiErr.Failures["invalid-item"]; err == nil || !strings.Contains(err.Error(), "Payload is required") {
+		t.Errorf("Expected a validation error for invalid-item, got %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, res := range responses {
+		got[res.DeviceToken] = true
+	}
+	if len(responses) != 2 || !got["token-a"] || !got["token-b"] {
+		t.Errorf("Expected successes for token-a and token-b, got %v", got)
+	}
+	if want := `{"aps":{"alert":"hello a"}} ca`; bodies["token-a"] != want {
+		t.Errorf("token-a: got %q, want %q", bodies["token-a"], want)
+	}
+	if want := `{"aps":{"alert":"hello b"}} cb`; bodies["token-b"] != want {
+		t.Errorf("token-b: got %q, want %q", bodies["token-b"], want)
+	}
+	if _, ok := bodies["invalid-item"]; ok {
+		t.Error("Expected the invalid item not to be sent")
+	}
+	if items[0].Notification.DeviceToken != "" {
+		t.Error("Expected the item notifications to be unchanged")
+	}
+
+	if _, err := client.PushBatch(context.Background(), []BatchItem{{Token: "token-a"}, {Token: "token-a", Notification: newNotification("x", "")}}); err == nil ||
+		!strings.Contains(err.Error(), "notification at index 0 is nil") || !strings.Contains(err.Error(), "duplicate token") {
+		t.Errorf("Expected aggregated input errors, got %v", err)
+	}
+}
+
+// countingMarshaler is a PayloadMarshaler that counts its calls and is safe for
+// concurrent use.
+type countingMarshaler struct {
+	calls atomic.Int32
+}
+
+func (m *countingMarshaler) Marshal(p *Payload) ([]byte, error) {
+	m.calls.Add(1)
+	return p.Marshal(false)
+}
+
+func TestClient_PushBatch_SharedNotification(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[path.Base(r.URL.Path)] = string(b)
+		mu.Unlock()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	m := &countingMarshaler{}
+	client.Marshaler = m
+	client.MarshalConcurrency = 4
+
+	shared := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "shared"}},
+	}
+	items := make([]BatchItem, 0, 11)
+	for i := range 10 {
+		items = append(items, BatchItem{Token: fmt.Sprintf("token-%d", i), Notification: shared})
+	}
+	items = append(items, BatchItem{Token: "token-own", Notification: &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "own"}},
+	}})
+
+	responses, err := client.PushBatch(context.Background(), items)
+	if err != nil {
+		t.Fatalf("PushBatch failed: %v", err)
+	}
+	if len(responses) != len(items) {
+		t.Errorf("Expected %d responses, got %d", len(items), len(responses))
+	}
+	if got := m.calls.Load(); got != 2 {
+		t.Errorf("Expected 2 marshal calls, got %d", got)
+	}
+	for i := range 10 {
+		token := fmt.Sprintf("token-%d", i)
+		if want := `{"aps":{"alert":"shared"}}`; bodies[token] != want {
+			t.Errorf("%s: got %q, want %q", token, bodies[token], want)
+		}
+	}
+	if want := `{"aps":{"alert":"own"}}`; bodies["token-own"] != want {
+		t.Errorf("token-own: got %q, want %q", bodies["token-own"], want)
+	}
+	if shared.DeviceToken != "" {
+		t.Error("Expected the shared notification to be unchanged")
+	}
+
+	// Transformers may depend on the device token, so each item is prepared on its own.
+	m.calls.Store(0)
+	client.Transformers = []func(*Notification) error{func(n *Notification) error {
+		n.CollapseID = n.DeviceToken
+		return nil
+	}}
+	if _, err := client.PushBatch(context.Background(), items); err != nil {
+		t.Fatalf("PushBatch failed: %v", err)
+	}
+	if got := m.calls.Load(); got != int32(len(items)) {
+		t.Errorf("Expected %d marshal calls with transformers, got %d", len(items), got)
+	}
+}
+
+func TestClient_PushMulti_Transformers(t *testing.T) {
+	var mu sync.Mutex
+	collapseIDs := make(map[string]string)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		collapseIDs[path.Base(r.URL.Path)] = r.Header.Get("apns-collapse-id")
+		mu.Unlock()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	errRejected := errors.New("rejected")
+	// Transformers may depend on the device token, so they run for each token
+	// as in PushBatch.
+	client.Transformers = []func(*Notification) error{func(n *Notification) error {
+		if n.DeviceToken == "token-rejected" {
+			return errRejected
+		}
+		n.CollapseID = n.DeviceToken
+		return nil
+	}}
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	responses, err := client.PushMulti(context.Background(), n, []string{"token-1", "token-rejected", "token-2"})
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || multiErr.Len() != 1 || !errors.Is(multiErr.Failures["token-rejected"], errRejected) {
+		t.Fatalf("PushMulti() error = %v, want a *MultiError for token-rejected", err)
+	}
+	if len(responses) != 2 {
+		t.Errorf("Expected 2 responses, got %d", len(responses))
+	}
+	want := map[string]string{"token-1": "token-1", "token-2": "token-2"}
+	if !maps.Equal(collapseIDs, want) {
+		t.Errorf("apns-collapse-id headers = %v, want %v", collapseIDs, want)
+	}
+	if n.CollapseID != "" {
+		t.Error("Expected the caller's notification to be unchanged")
+	}
+}
+
+func TestClient_DedupeTokens(t *testing.T) {
+	var mu sync.Mutex
+	sent := make(map[string]int)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		sent[path.Base(r.URL.Path)+" "+r.Header.Get("apns-collapse-id")]++
+		mu.Unlock()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	client.DedupeTokens = true
+
+	newNotification := func(collapseID string) *Notification {
+		return &Notification{
+			BundleID:   "com.example.app",
+			Type:       notification.Alert,
+			CollapseID: collapseID,
+			Payload:    &Payload{APS: payload.APS{Alert: "test"}},
+		}
+	}
+	// check compares the tokens of the Deduplicated responses with wantDeduped
+	// and checks that each of wantSent, a token and collapse ID, was sent once.
+	check := func(t *testing.T, responses []*Response, wantSent, wantDeduped []string) {
+		t.Helper()
+		var deduped []string
+		for _, res := range responses {
+			if res.Deduplicated {
+				deduped = append(deduped, res.DeviceToken)
+			}
+		}
+		if !slices.Equal(deduped, wantDeduped) {
+			t.Errorf("Expected deduplicated tokens %v, got %v", wantDeduped, deduped)
+		}
+		if len(responses) != len(wantSent)+len(wantDeduped) {
+			t.Errorf("Expected %d responses, got %d", len(wantSent)+len(wantDeduped), len(responses))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, key := range wantSent {
+			if sent[key] != 1 {
+				t.Errorf("Expected %q to be sent once, got %d", key, sent[key])
+			}
+		}
+		if len(sent) != len(wantSent) {
+			t.Errorf("Expected %d distinct requests, got %v", len(wantSent), sent)
+		}
+		clear(sent)
+	}
+
+	t.Run("PushMulti", func(t *testing.T) {
+		tokens := []string{"t1", "t2", "t1", "t3", "t2", "t1"}
+		responses, err := client.PushMulti(context.Background(), newNotification(""), tokens)
+		if err != nil {
+			t.Fatalf("PushMulti failed: %v", err)
+		}
+		check(t, responses, []string{"t1 ", "t2 ", "t3 "}, []string{"t1", "t2", "t1"})
+	})
+
+	t.Run("PushChunked", func(t *testing.T) {
+		tokens := []string{"t1", "t2", "t1", "t3"}
+		result, err := client.PushChunked(context.Background(), newNotification(""), tokens, 2)
+		if err != nil {
+			t.Fatalf("PushChunked failed: %v", err)
+		}
+		if len(result.Chunks) != 2 {
+			t.Errorf("Expected 2 chunks of the unique tokens, got %d", len(result.Chunks))
+		}
+		check(t, result.Responses(), []string{"t1 ", "t2 ", "t3 "}, []string{"t1"})
+	})
+
+	t.Run("PushBatch", func(t *testing.T) {
+		shared := newNotification("")
+		items := []BatchItem{
+			{Token: "t1", Notification: shared},
+			{Token: "t1", Notification: shared},
+			{Token: "t2", Notification: newNotification("c1")},
+			{Token: "t2", Notification: newNotification("c1")},
+			{Token: "t3", Notification: newNotification("c1")},
+		}
+		responses, err := client.PushBatch(context.Background(), items)
+		if err != nil {
+			t.Fatalf("PushBatch failed: %v", err)
+		}
+		check(t, responses, []string{"t1 ", "t2 c1", "t3 c1"}, []string{"t1", "t2"})
+
+		// The same token with different notifications is still rejected.
+		items = []BatchItem{
+			{Token: "t1", Notification: newNotification("c1")},
+			{Token: "t1", Notification: newNotification("c2")},
+		}
+		if _, err := client.PushBatch(context.Background(), items); err == nil || !strings.Contains(err.Error(), "duplicate token") {
+			t.Errorf("Expected a duplicate token error, got %v", err)
+		}
+	})
+}
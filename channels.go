@@ -0,0 +1,129 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// ManagementProductionHost is the production server hostname for managing
+	// broadcast channels.
+	ManagementProductionHost = "https://api-manage-broadcast.push.apple.com:2196"
+	// ManagementDevelopmentHost is the development server hostname for managing
+	// broadcast channels.
+	ManagementDevelopmentHost = "https://api-manage-broadcast.sandbox.push.apple.com:2195"
+)
+
+// CreateChannel creates a broadcast channel for the Live Activities of the app
+// and returns its ID, to be used as Notification.ChannelID. Messages sent to the
+// channel are not stored for devices that are offline.
+func (cli *Client) CreateChannel(ctx context.Context, bundleID string) (string, error) {
+	body := []byte(`{"message-storage-policy":0,"push-type":"LiveActivity"}`)
+	header, _, err := cli.manageChannel(ctx, http.MethodPost, bundleID, "/channels", "", body)
+	if err != nil {
+		return "", err
+	}
+	channelID := header.Get("apns-channel-id")
+	if channelID == "" {
+		return "", errors.New("APNs channel creation: response has no apns-channel-id")
+	}
+	return channelID, nil
+}
+
+// ListChannels returns the IDs of all broadcast channels of the app.
+func (cli *Client) ListChannels(ctx context.Context, bundleID string) ([]string, error) {
+	_, body, err := cli.manageChannel(ctx, http.MethodGet, bundleID, "/all-channels", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Channels []string `json:"channels"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse channel list: %w", err)
+	}
+	return res.Channels, nil
+}
+
+// DeleteChannel deletes the broadcast channel of the app.
+func (cli *Client) DeleteChannel(ctx context.Context, bundleID, channelID string) error {
+	if channelID == "" {
+		return errors.New("channelID is required")
+	}
+	_, _, err := cli.manageChannel(ctx, http.MethodDelete, bundleID, "/channels", channelID, nil)
+	return err
+}
+
+// manageChannel sends a request to the channel management endpoint of the app
+// and returns the header and body of a successful (2xx) response. Like pushes,
+// the request is subject to RequestTimeout and counted in Stats.
+func (cli *Client) manageChannel(ctx context.Context, method, bundleID, endpoint, channelID string, body []byte) (http.Header, []byte, error) {
+	cli.freeze()
+	if bundleID == "" {
+		return nil, nil, errors.New("BundleID is required")
+	}
+	path := cli.managementHost() + "/1/apps/" + url.PathEscape(bundleID) + endpoint
+	req, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if channelID != "" {
+		req.Header.Set("apns-channel-id", channelID)
+	}
+
+	if cli.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.RequestTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	var header http.Header
+	var respBody []byte
+	err = cli.track(req, func() (int, error) {
+		resp, err := cli.do(req)
+		if err != nil {
+			return 0, fmt.Errorf("failed to send APNs channel request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		respBody, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			err := responseError(resp.StatusCode, respBody)
+			if apnsErr, ok := err.(*Error); ok && cli.CaptureResponseBody {
+				apnsErr.RawBody = respBody
+			}
+			return resp.StatusCode, err
+		}
+		header = resp.Header
+		return resp.StatusCode, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, respBody, nil
+}
+
+// managementHost returns the base URL of the channel management endpoints.
+func (cli *Client) managementHost() string {
+	if cli.ManagementHost != "" {
+		return cli.ManagementHost
+	}
+	if cli.inner.Development {
+		return ManagementDevelopmentHost
+	}
+	return ManagementProductionHost
+}
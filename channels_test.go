@@ -0,0 +1,157 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/appleapi-core"
+)
+
+func TestClient_Channels(t *testing.T) {
+	channels := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Expected authorization header, got %q", got)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/1/apps/com.example.app/channels":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"message-storage-policy":0,"push-type":"LiveActivity"}` {
+				t.Errorf("Unexpected body %s", body)
+			}
+			id := "channel-" + string(rune('a'+len(channels)))
+			channels = append(channels, id)
+			w.Header().Set("apns-channel-id", id)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/1/apps/com.example.app/all-channels":
+			w.Header().Set("Content-Type", "application/json")
+			body := `{"channels":[`
+			for i, id := range channels {
+				if i > 0 {
+					body += ","
+				}
+				body += `"` + id + `"`
+			}
+			_, _ = io.WriteString(w, body+"]}")
+		case r.Method == http.MethodDelete && r.URL.Path == "/1/apps/com.example.app/channels":
+			i := slices.Index(channels, r.Header.Get("apns-channel-id"))
+			if i < 0 {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"reason":"ChannelNotRegistered"}`)
+				return
+			}
+			channels = slices.Delete(channels, i, i+1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.ManagementHost = server.URL
+	ctx := context.Background()
+
+	for _, want := range []string{"channel-a", "channel-b"} {
+		id, err := client.CreateChannel(ctx, "com.example.app")
+		if err != nil {
+			t.Fatalf("CreateChannel failed: %v", err)
+		}
+		if id != want {
+			t.Errorf("CreateChannel() = %q, want %q", id, want)
+		}
+	}
+
+	got, err := client.ListChannels(ctx, "com.example.app")
+	if err != nil {
+		t.Fatalf("ListChannels failed: %v", err)
+	}
+	if !slices.Equal(got, []string{"channel-a", "channel-b"}) {
+		t.Errorf("ListChannels() = %v, want [channel-a channel-b]", got)
+	}
+
+	if err := client.DeleteChannel(ctx, "com.example.app", "channel-a"); err != nil {
+		t.Fatalf("DeleteChannel failed: %v", err)
+	}
+	err = client.DeleteChannel(ctx, "com.example.app", "channel-a")
+	var apnsErr *Error
+	if !errors.As(err, &apnsErr) || apnsErr.StatusCode != http.StatusNotFound || apnsErr.Reason != "ChannelNotRegistered" {
+		t.Errorf("DeleteChannel() error = %v, want ChannelNotRegistered", err)
+	}
+
+	got, err = client.ListChannels(ctx, "com.example.app")
+	if err != nil {
+		t.Fatalf("ListChannels failed: %v", err)
+	}
+	if !slices.Equal(got, []string{"channel-b"}) {
+		t.Errorf("ListChannels() = %v, want [channel-b]", got)
+	}
+
+	if _, err := client.CreateChannel(ctx, ""); err == nil {
+		t.Error("CreateChannel with an empty bundle ID: expected an error")
+	}
+	if err := client.DeleteChannel(ctx, "com.example.app", ""); err == nil {
+		t.Error("DeleteChannel with an empty channel ID: expected an error")
+	}
+}
+
+func TestClient_Channels_Tracked(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodDelete {
+			// Hang until the request times out.
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+		res := newResponse(http.StatusCreated, "")
+		res.Header.Set("apns-channel-id", "channel-a")
+		return res, nil
+	})
+	client := newTestClient(t, transport)
+	client.ManagementHost = "https://localhost:2196"
+	client.RequestTimeout = 10 * time.Millisecond
+	var results []int
+	client.OnResult = func(pushType string, statusCode int, reason string, latency time.Duration) {
+		results = append(results, statusCode)
+	}
+	ctx := context.Background()
+
+	if _, err := client.CreateChannel(ctx, "com.example.app"); err != nil {
+		t.Fatalf("CreateChannel failed: %v", err)
+	}
+	if err := client.DeleteChannel(ctx, "com.example.app", "channel-a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeleteChannel() error = %v, want the RequestTimeout to expire", err)
+	}
+	if want := (Stats{Sent: 2, Succeeded: 1, Failed: 1}); client.Stats() != want {
+		t.Errorf("Stats() = %+v, want %+v", client.Stats(), want)
+	}
+	if !slices.Equal(results, []int{http.StatusCreated, 0}) {
+		t.Errorf("OnResult status codes = %v, want [201 0]", results)
+	}
+}
+
+func TestClient_managementHost(t *testing.T) {
+	prod, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if got := prod.managementHost(); got != ManagementProductionHost {
+		t.Errorf("managementHost() = %q, want %q", got, ManagementProductionHost)
+	}
+	dev, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"}, appleapi.WithDevelopment())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if got := dev.managementHost(); got != ManagementDevelopmentHost {
+		t.Errorf("managementHost() = %q, want %q", got, ManagementDevelopmentHost)
+	}
+}
@@ -0,0 +1,125 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/takimoto3/apns/payload"
+)
+
+// Disposition is the action a caller should take for a failed push, as reported by Classify.
+type Disposition int
+
+const (
+	// Unknown means the error could not be classified.
+	Unknown Disposition = iota
+	// Delete means the device token is no longer valid and should be removed.
+	Delete
+	// Retry means the failure is transient and the push can be sent again later.
+	Retry
+	// Permanent means the request itself is wrong, usually because of a
+	// configuration or programming error, and will fail again if retried as is.
+	Permanent
+)
+
+// String returns the name of the disposition.
+func (d Disposition) String() string {
+	switch d {
+	case Delete:
+		return "Delete"
+	case Retry:
+		return "Retry"
+	case Permanent:
+		return "Permanent"
+	default:
+		return "Unknown"
+	}
+}
+
+// reasonDispositions maps the error reasons documented by Apple to dispositions.
+// https://developer.apple.com/documentation/usernotifications/handling-notification-responses-from-apns
+var reasonDispositions = map[string]Disposition{
+	// The device token is invalid, expired or no longer registered.
+	"BadDeviceToken": Delete,
+	"ExpiredToken":   Delete,
+	"Unregistered":   Delete,
+
+	// Transient server or rate-limiting conditions.
+	"ExpiredProviderToken":        Retry,
+	"IdleTimeout":                 Retry,
+	"InternalServerError":         Retry,
+	"ServiceUnavailable":          Retry,
+	"Shutdown":                    Retry,
+	"TooManyProviderTokenUpdates": Retry,
+	"TooManyRequests":             Retry,
+
+	// Malformed requests and credential or topic configuration errors. A token
+	// that is not for the topic is usually sent with the wrong topic, and is
+	// valid for the topic of its app, so it is not to be deleted.
+	"BadCertificate":             Permanent,
+	"BadCertificateEnvironment":  Permanent,
+	"BadCollapseId":              Permanent,
+	"BadEnvironmentKeyIdInToken": Permanent,
+	"BadExpirationDate":          Permanent,
+	"BadMessageId":               Permanent,
+	"BadPath":                    Permanent,
+	"BadPriority":                Permanent,
+	"BadTopic":                   Permanent,
+	"DeviceTokenNotForTopic":     Permanent,
+	"DuplicateHeaders":           Permanent,
+	"Forbidden":                  Permanent,
+	"InvalidProviderToken":       Permanent,
+	"InvalidPushType":            Permanent,
+	"MethodNotAllowed":           Permanent,
+	"MissingDeviceToken":         Permanent,
+	"MissingProviderToken":       Permanent,
+	"MissingTopic":               Permanent,
+	"PayloadEmpty":               Permanent,
+	"PayloadTooLarge":            Permanent,
+	"TopicDisallowed":            Permanent,
+	"UnrelatedKeyIdInToken":      Permanent,
+}
+
+// Classify maps an error returned by Push and related methods, or one of the
+// failures of a *MultiError, to the action the caller should take:
+//
+//   - APNs errors are classified by their Reason, e.g. Unregistered and
+//     BadDeviceToken as Delete, TooManyRequests and ServiceUnavailable as Retry,
+//     and BadTopic and MissingTopic as Permanent. Unknown reasons fall back to
+//     the status code.
+//   - Transport errors, where no response was received, are Retry.
+//   - Authentication errors, validation errors and ErrHTTP2Required are Permanent.
+//
+// Any other error, including nil, is Unknown.
+func Classify(err error) Disposition {
+	if err == nil {
+		return Unknown
+	}
+	var apnsErr *Error
+	if errors.As(err, &apnsErr) {
+		if d, ok := reasonDispositions[apnsErr.Reason]; ok {
+			return d
+		}
+		switch apnsErr.StatusCode {
+		case http.StatusGone:
+			return Delete
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+			return Retry
+		}
+		return Unknown
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return Permanent
+	}
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		return Retry
+	}
+	var validationErr *payload.ValidationError
+	if errors.As(err, &validationErr) || errors.Is(err, ErrHTTP2Required) {
+		return Permanent
+	}
+	return Unknown
+}
@@ -0,0 +1,103 @@
+package apns
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestClassify_Reasons(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		want   Disposition
+	}{
+		"BadCollapseId":               {http.StatusBadRequest, Permanent},
+		"BadDeviceToken":              {http.StatusBadRequest, Delete},
+		"BadExpirationDate":           {http.StatusBadRequest, Permanent},
+		"BadMessageId":                {http.StatusBadRequest, Permanent},
+		"BadPriority":                 {http.StatusBadRequest, Permanent},
+		"BadTopic":                    {http.StatusBadRequest, Permanent},
+		"DeviceTokenNotForTopic":      {http.StatusBadRequest, Permanent},
+		"DuplicateHeaders":            {http.StatusBadRequest, Permanent},
+		"IdleTimeout":                 {http.StatusBadRequest, Retry},
+		"InvalidPushType":             {http.StatusBadRequest, Permanent},
+		"MissingDeviceToken":          {http.StatusBadRequest, Permanent},
+		"MissingTopic":                {http.StatusBadRequest, Permanent},
+		"PayloadEmpty":                {http.StatusBadRequest, Permanent},
+		"TopicDisallowed":             {http.StatusBadRequest, Permanent},
+		"BadCertificate":              {http.StatusForbidden, Permanent},
+		"BadCertificateEnvironment":   {http.StatusForbidden, Permanent},
+		"ExpiredProviderToken":        {http.StatusForbidden, Retry},
+		"Forbidden":                   {http.StatusForbidden, Permanent},
+		"InvalidProviderToken":        {http.StatusForbidden, Permanent},
+		"MissingProviderToken":        {http.StatusForbidden, Permanent},
+		"UnrelatedKeyIdInToken":       {http.StatusForbidden, Permanent},
+		"BadEnvironmentKeyIdInToken":  {http.StatusForbidden, Permanent},
+		"BadPath":                     {http.StatusNotFound, Permanent},
+		"MethodNotAllowed":            {http.StatusMethodNotAllowed, Permanent},
+		"ExpiredToken":                {http.StatusGone, Delete},
+		"Unregistered":                {http.StatusGone, Delete},
+		"PayloadTooLarge":             {http.StatusRequestEntityTooLarge, Permanent},
+		"TooManyProviderTokenUpdates": {http.StatusTooManyRequests, Retry},
+		"TooManyRequests":             {http.StatusTooManyRequests, Retry},
+		"InternalServerError":         {http.StatusInternalServerError, Retry},
+		"ServiceUnavailable":          {http.StatusServiceUnavailable, Retry},
+		"Shutdown":                    {http.StatusServiceUnavailable, Retry},
+	}
+
+	for reason, tt := range tests {
+		t.Run(reason, func(t *testing.T) {
+			err := &Error{StatusCode: tt.status, Reason: reason}
+			if got := Classify(err); got != tt.want {
+				t.Errorf("Classify(%s) = %v, want %v", reason, got, tt.want)
+			}
+		})
+	}
+	if len(tests) != len(reasonDispositions) {
+		t.Errorf("Expected every documented reason to be tested: tested %d, classified %d", len(tests), len(reasonDispositions))
+	}
+}
+
+func TestClassify_Errors(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want Disposition
+	}{
+		"nil":                      {nil, Unknown},
+		"undocumented reason, 410": {&Error{StatusCode: http.StatusGone, Reason: "SomethingNew"}, Delete},
+		"undocumented reason, 503": {&Error{StatusCode: http.StatusServiceUnavailable, Reason: "SomethingNew"}, Retry},
+		"undocumented reason, 400": {&Error{StatusCode: http.StatusBadRequest, Reason: "SomethingNew"}, Unknown},
+		"wrapped APNs error":       {fmt.Errorf("chunk failed: %w", &Error{StatusCode: http.StatusGone, Reason: "Unregistered"}), Delete},
+		"transport error":          {&TransportError{DeviceToken: "token", Err: errors.New("connection reset")}, Retry},
+		"authentication error":     {&AuthError{Err: errors.New("invalid key")}, Permanent},
+		"validation error":         {&payload.ValidationError{Field: "event", Code: payload.CodeInvalidValue}, Permanent},
+		"HTTP/2 required":          {fmt.Errorf("protocol: %w", ErrHTTP2Required), Permanent},
+		"unrelated error":          {errors.New("boom"), Unknown},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisposition_String(t *testing.T) {
+	tests := map[Disposition]string{
+		Unknown:         "Unknown",
+		Delete:          "Delete",
+		Retry:           "Retry",
+		Permanent:       "Permanent",
+		Disposition(42): "Unknown",
+	}
+	for d, want := range tests {
+		if got := d.String(); got != want {
+			t.Errorf("Disposition(%d).String() = %q, want %q", int(d), got, want)
+		}
+	}
+}
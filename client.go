@@ -11,14 +11,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"maps"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/takimoto3/apns/certificate"
 	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/notification/priority"
+	"github.com/takimoto3/apns/payload"
 	"github.com/takimoto3/appleapi-core"
 	"github.com/takimoto3/appleapi-core/token"
 )
@@ -32,18 +39,86 @@ const (
 	// Path is the URL path for sending a notification.
 	Path = "/3/device/"
 
+	// BroadcastPath is the URL path, followed by the bundle ID, for broadcasting
+	// a notification to a channel.
+	BroadcastPath = "/4/broadcasts/apps/"
+
 	MaxTokens = 100
+
+	// MaxPayloadSize is the maximum size in bytes of a notification payload.
+	MaxPayloadSize = 4096
+	// MaxVoIPPayloadSize is the maximum size in bytes of a VoIP notification payload.
+	MaxVoIPPayloadSize = 5120
+
+	// MaxCollapseIDSize is the maximum size in bytes of the apns-collapse-id header.
+	MaxCollapseIDSize = 64
+
+	// DefaultMaxExpiration is the furthest in the future an expiration may be
+	// under strict validation, unless Client.MaxExpiration says otherwise.
+	DefaultMaxExpiration = 30 * 24 * time.Hour
 )
 
+// ErrHTTP2Required is returned when a request to APNs was not sent over HTTP/2,
+// for example because a proxy in between only speaks HTTP/1.1.
+var ErrHTTP2Required = errors.New("APNs requires HTTP/2")
+
+// ErrClientClosed is returned when a request is sent with a Client that has been closed.
+var ErrClientClosed = errors.New("client closed")
+
+// ErrInvalidJSON is returned when Client.ValidateJSON is set and a marshaled
+// payload is not valid JSON, which indicates a bug in the encoder or marshaler.
+var ErrInvalidJSON = errors.New("marshaled payload is not valid JSON")
+
+// ErrUnregistered matches, with errors.Is, an *Error with the reason
+// "Unregistered": the device token is no longer active for the topic.
+var ErrUnregistered = errors.New("APNs: device token is unregistered")
+
+// ErrBadDeviceToken matches, with errors.Is, an *Error with the reason
+// "BadDeviceToken": the device token is invalid, e.g. for the wrong environment.
+var ErrBadDeviceToken = errors.New("APNs: bad device token")
+
 // MultiError holds a collection of errors that occurred during a batch operation.
 type MultiError struct {
 	// Failures is a map where the key is the device token that failed and the value is the error.
 	Failures map[string]error
 }
 
-// Error implements the error interface.
+// FailureEntry is a single failure of a batch operation.
+type FailureEntry struct {
+	// Token is the device token that failed.
+	Token string
+	// Err is the error for the token.
+	Err error
+}
+
+// Error implements the error interface. The tokens are listed in sorted order.
 func (e *MultiError) Error() string {
-	return fmt.Sprintf("APNs batch failed for tokens: %v", maps.Keys(e.Failures))
+	return fmt.Sprintf("APNs batch failed for tokens: %v", slices.Sorted(maps.Keys(e.Failures)))
+}
+
+// Unwrap returns the failures sorted by device token, so that errors.Is and
+// errors.As match if any of the failures matches.
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, entry := range e.Entries() {
+		errs = append(errs, entry.Err)
+	}
+	return errs
+}
+
+// Len returns the number of failures.
+func (e *MultiError) Len() int {
+	return len(e.Failures)
+}
+
+// Entries returns the failures sorted by device token, giving a stable order
+// for logging or persisting them.
+func (e *MultiError) Entries() []FailureEntry {
+	entries := make([]FailureEntry, 0, len(e.Failures))
+	for _, token := range slices.Sorted(maps.Keys(e.Failures)) {
+		entries = append(entries, FailureEntry{Token: token, Err: e.Failures[token]})
+	}
+	return entries
 }
 
 // Error represents an error response from the APNs server.
@@ -55,6 +130,9 @@ type Error struct {
 	// Timestamp is the time at which the error occurred, in milliseconds since Unix epoch.
 	// This field may be zero if the server did not provide a timestamp.
 	Timestamp int64
+	// RawBody is the body of the response as received, for debugging unusual
+	// responses. It is only set if the client's CaptureResponseBody is true.
+	RawBody []byte
 }
 
 // Error returns a string representation of the Error.
@@ -65,6 +143,18 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("APNs error: status=%d reason=%s", e.StatusCode, e.Reason)
 }
 
+// Is reports whether the error has the reason of target, one of the sentinel
+// errors such as ErrUnregistered.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnregistered:
+		return e.Reason == "Unregistered"
+	case ErrBadDeviceToken:
+		return e.Reason == "BadDeviceToken"
+	}
+	return false
+}
+
 func (e *Error) TimeStamp() *time.Time {
 	if e.Timestamp == 0 {
 		return nil
@@ -73,23 +163,105 @@ func (e *Error) TimeStamp() *time.Time {
 	return &tms
 }
 
+// TransportError is returned when a request fails before a response is received
+// from APNs, e.g. because the connection failed. It carries the metadata of the
+// request, including the apns-id sent with it, so that callers can correlate
+// the failed send for retries or deduplication.
+type TransportError struct {
+	// DeviceToken is the device token the request was sent to.
+	DeviceToken string
+	// ChannelID is the apns-channel-id of a broadcast request.
+	ChannelID string
+	// APNsID is the apns-id sent with the request. It is empty if the notification
+	// had no APNsID and AutoGenerateAPNsID was not set.
+	APNsID string
+	// PushType is the apns-push-type sent with the request.
+	PushType string
+	// Topic is the apns-topic sent with the request.
+	Topic string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns a string representation of the TransportError.
+func (e *TransportError) Error() string {
+	return "failed to send APNs request: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError is returned when the token provider of a token-based client fails
+// to produce an authentication token, e.g. because the signing key was revoked
+// or cannot be parsed. The request is not sent. It is distinct from a
+// TransportError, so that callers can tell credential failures, which need
+// attention, from network failures.
+type AuthError struct {
+	// Err is the error returned by the token provider.
+	Err error
+}
+
+// Error returns a string representation of the AuthError.
+func (e *AuthError) Error() string {
+	return "failed to get APNs authentication token: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
 // Response represents a successful response from the APNs server.
 type Response struct {
 	// DeviceToken is the device token for which the notification was successfully sent.
 	DeviceToken string
-	// UniqueID is the unique ID of the notification, returned for development builds.
-	// This is the same as apns-unique-id.
+	// UniqueID is the unique ID of the notification, which can be used to look up
+	// the delivery in Apple's logs. It is set from the apns-unique-id response header
+	// in both the development and production environments.
 	UniqueID string
 	// APNsID is the canonical UUID of the notification.
 	// This is the same as apns-id.
 	APNsID string
+	// StatusCode is the HTTP status code of the response, 200 on success. It is
+	// zero if the notification was Deduplicated.
+	StatusCode int
+	// Deduplicated is true if the notification was not sent because the same
+	// notification had already been sent within the client's DedupWindow, or
+	// because its device token was a duplicate in a batch sent with DedupeTokens.
+	Deduplicated bool
+	// Body is the JSON body of a successful response. APNs currently sends no body
+	// on success, so it is only set if the client's CaptureResponseBody is true and
+	// a 200 response carries a valid JSON body (e.g. metadata added by a proxy).
+	Body json.RawMessage
+	// Headers holds all headers of the response, including apns-id and any
+	// diagnostic headers APNs adds. It is only set if the client's
+	// CaptureHeaders is true, for successful and failed responses alike.
+	Headers http.Header
+	// ReceivedAt is the time, according to the client's clock, at which the
+	// response was received. It is zero if the notification was Deduplicated.
+	ReceivedAt time.Time
 }
 
 // Client is a client for sending notifications to the APNs.
+//
+// A Client is safe for concurrent use by multiple goroutines. The settings
+// applied with Configure are fixed once the client is first used: Configure then fails with ErrClientInUse. Its exported
+// fields are settings too, which, like the fields of http.Server, must be set
+// before the client is shared and not modified afterwards, since the push methods
+// read them without synchronization. Some of them can also be set by Options,
+// e.g. TokenLimits by WithTokenLimits. To change the authentication of a client
+// in use, call SetCertificate or SetTokenProvider, which are safe to call
+// concurrently with pushes.
 type Client struct {
-	inner       *appleapi.Client
+	inner *appleapi.Client
+	// TokenLimits is the maximum number of device tokens accepted by PushMulti
+	// and PushBatch, and the largest chunk size of PushChunked. Defaults to MaxTokens.
 	TokenLimits int
-	TokenBase   bool
+	// TokenBase reports whether the client uses token-based authentication. It
+	// is maintained by the client and must not be set directly.
+	TokenBase bool
 
 	// FastJson, if true, uses a high-performance custom JSON encoder for the payload.
 	// This encoder is faster than the standard `encoding/json` but supports a limited
@@ -97,6 +269,182 @@ type Client struct {
 	// See the documentation for `payload.MarshalJSONFast` for more details.
 	// Defaults to true.
 	FastJson bool
+
+	// Marshaler, if set, encodes notification payloads in place of the encoder
+	// selected by FastJson.
+	Marshaler PayloadMarshaler
+
+	// RequestTimeout, if greater than zero, bounds the duration of each individual
+	// request sent by Push and PushMulti. A shorter deadline on the caller's context
+	// still takes precedence. Unlike the HTTP client timeout, it applies per request.
+	RequestTimeout time.Duration
+
+	// Transformers are applied in order to every notification before it is
+	// validated and sent, once for each device token by PushMulti and PushBatch.
+	// They operate on a clone, so the caller's notification is never modified.
+	// If a transformer returns an error, the notification is not sent.
+	Transformers []func(*Notification) error
+
+	// DedupWindow, if greater than zero, suppresses resending a notification with
+	// the same device token and APNsID within the window. A suppressed send returns
+	// a Response with Deduplicated set to true without contacting APNs.
+	// Notifications without an APNsID are never deduplicated.
+	DedupWindow time.Duration
+
+	// DedupeTokens, if true, drops duplicate device tokens from the token lists of
+	// PushMulti and PushChunked, and duplicate items from PushBatch, instead of
+	// rejecting the whole batch. Each dropped duplicate is reported by a Response
+	// with Deduplicated set to true. In PushBatch, an item is a duplicate if an
+	// earlier item has the same token and the same Notification or the same
+	// non-empty CollapseID; items for the same token that differ otherwise are
+	// still rejected.
+	DedupeTokens bool
+
+	// Gateway indicates that requests are sent through an APNs-compatible gateway
+	// or proxy instead of directly to APNs. Direct connections to APNs must use
+	// HTTP/2; setting Gateway disables that check.
+	Gateway bool
+
+	// CaptureResponseBody, if true, surfaces the JSON body of a 200 response in
+	// Response.Body and the raw body of an error response in Error.RawBody
+	// instead of discarding them. Defaults to false.
+	CaptureResponseBody bool
+
+	// CaptureHeaders, if true, surfaces a copy of all response headers in
+	// Response.Headers, e.g. for debugging. Defaults to false.
+	CaptureHeaders bool
+
+	// AutoGenerateAPNsID, if true, assigns a new random UUID as the APNsID of every
+	// notification sent without one, so that the ID is known before the request is
+	// sent and can be used for logging and correlating retries. The caller's
+	// notification is not modified; the ID is returned in Response.APNsID.
+	//
+	// An APNsID set on the notification, by the caller or by a Transformer, always
+	// takes precedence and is sent unchanged. A generated ID is kept for all retries
+	// of the same notification.
+	AutoGenerateAPNsID bool
+
+	// DowngradeAfterThrottles, if greater than zero, lowers the priority of
+	// notifications from Immediate (or the default) to Conserve after that many
+	// consecutive 429 TooManyRequests responses, and restores it after the next
+	// successful request. It only applies to silent notifications, whose
+	// payload is content-available only, of the `alert`, `background`,
+	// `liveactivity` and `widgets` push types, for which a Conserve priority is
+	// allowed and only affects delivery timing. Notifications that alert the
+	// user always keep their priority.
+	DowngradeAfterThrottles int
+
+	// MaxRetries is the number of times a notification is resent after a transport
+	// error or a 429, 500 or 503 response. Defaults to 0 (no retries).
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled for each following
+	// retry. If zero, DefaultRetryBackoff is used.
+	RetryBackoff time.Duration
+
+	// RetryGoAway, if true, sends a request once more on a new connection,
+	// regardless of MaxRetries, if it fails because APNs closed its connection
+	// with a GOAWAY frame while the request was in flight. Requests that APNs
+	// had not started processing are retried by the HTTP/2 transport anyway;
+	// the requests this retries may already have been processed, so their
+	// notification may be delivered twice. Set an apns-collapse-id on
+	// notifications for which a duplicate must replace the first delivery.
+	RetryGoAway bool
+
+	// RetryBudget, if greater than zero, caps the total number of retries across
+	// all tokens of a single PushMulti, PushChunked or PushBatch call, so that an
+	// APNs incident does not turn into a retry storm. Once it is exhausted,
+	// failures are returned without further retries.
+	RetryBudget int
+
+	// MarshalConcurrency bounds the number of notifications PushBatch validates
+	// and marshals at the same time. Defaults to runtime.GOMAXPROCS(0) if zero or negative.
+	MarshalConcurrency int
+
+	// Limiter, if set, is waited on before each request of PushMulti, PushChunked
+	// and PushBatch to smooth bursts that could trigger TooManyRequests, e.g.
+	// rate.NewLimiter(1000, 100). A request fails with the error returned by Wait,
+	// e.g. when its context is done while waiting. Push is not limited.
+	Limiter Limiter
+
+	// ValidateJSON, if true, checks that every marshaled payload is valid JSON
+	// before it is sent, failing with ErrInvalidJSON otherwise. It is a safety net
+	// against encoder bugs, which APNs would otherwise reject with a 400.
+	ValidateJSON bool
+
+	// DefaultPushType, if set, is used as the push type of notifications whose
+	// Type is empty, e.g. notification.Alert. Notifications with an explicit
+	// Type, valid or not, are sent as is. The caller's notification is not modified.
+	DefaultPushType notification.PushType
+
+	// AutoPriority, if true, sends notifications without a Priority with the
+	// default of their push type: priority.Immediate for `alert` and `voip`
+	// pushes and priority.Conserve for `background` pushes. Other push types are
+	// left to APNs. An explicit Priority is always sent as is.
+	AutoPriority bool
+
+	// StrictValidation, if true, validates notifications with ValidateStrict
+	// instead of Validate before sending them, so that advisory failures also
+	// prevent the notification from being sent.
+	StrictValidation bool
+
+	// SkipValidation, if true, sends notifications without validating them,
+	// ignoring StrictValidation and MaxExpiration, to save the cost of validation
+	// for callers that validate their notifications once up front. The payload
+	// size is still checked, since APNs rejects oversized payloads anyway.
+	// Invalid notifications are rejected by APNs instead.
+	SkipValidation bool
+
+	// MaxExpiration, if greater than zero, rejects notifications whose Expiration
+	// is further than this in the future, even without StrictValidation. With
+	// StrictValidation, it replaces DefaultMaxExpiration.
+	MaxExpiration time.Duration
+
+	// OnResult, if set, is called after every request to APNs completes, whether
+	// it succeeded or not, e.g. to record metrics. statusCode is zero if no response
+	// was received, and reason is the APNs error reason, if any. It is called
+	// concurrently by PushMulti and PushBatch; a panic in it is recovered. It is
+	// not called for Ping. pushType is empty for broadcast channel management
+	// requests.
+	OnResult func(pushType string, statusCode int, reason string, latency time.Duration)
+
+	// PingTopic is the topic Ping sends its notification to, usually the bundle
+	// ID of the app. It is required with token-based authentication; with a
+	// certificate it may be empty to use the certificate's topic.
+	PingTopic string
+
+	// ManagementHost, if set, is the base URL used by CreateChannel, ListChannels
+	// and DeleteChannel in place of ManagementProductionHost or
+	// ManagementDevelopmentHost, which are selected by the environment.
+	ManagementHost string
+
+	// ContentType, if set, is sent verbatim as the Content-Type of every request,
+	// e.g. "application/json; charset=utf-8" for gateways that require it. APNs
+	// itself does not need a Content-Type, so none is sent by default.
+	ContentType string
+
+	// UserAgent is the User-Agent sent with every request. A User-Agent set in
+	// Notification.Headers takes precedence. Defaults to DefaultUserAgent().
+	UserAgent string
+
+	// Logger, if set, logs every request at debug level and every failed request
+	// at warn (4xx) or error level. Device tokens are redacted and the
+	// authorization header is never logged. Defaults to nil, which disables logging.
+	Logger *slog.Logger
+
+	stats    stats
+	dedup    dedupCache
+	throttle throttle
+	closed   atomic.Bool
+	inFlight *byteLimiter // set by WithMaxInFlightBytes
+
+	authMu   sync.RWMutex // guards TokenBase and inner.TokenProvider
+	cert     atomic.Pointer[tls.Certificate]
+	certHook bool
+	authGen  atomic.Uint64 // incremented when the certificate changes
+
+	configMu sync.Mutex  // serializes Configure and freeze
+	frozen   atomic.Bool // set once the client is in use
 }
 
 // NewClientWithToken creates a new APNs client that uses token-based authentication (.p8).
@@ -108,20 +456,30 @@ func NewClientWithToken(tp token.Provider, opts ...appleapi.Option) (*Client, er
 // NewClientWithCert creates a new APNs client that uses certificate-based authentication (.p12).
 // It requires a `tls.Certificate` which is used to authenticate with the APNs server.
 func NewClientWithCert(cert *tls.Certificate, opts ...appleapi.Option) (*Client, error) {
-	if cert == nil {
-		return nil, errors.New("certificate cannot be nil")
-	}
-	if len(cert.Certificate) == 0 || cert.PrivateKey == nil {
-		return nil, errors.New("invalid certificate: empty certificate or private key")
+	if err := validateCertificate(cert); err != nil {
+		return nil, err
 	}
 	config := appleapi.DefaultConfig()
 	config.TLSConfig = &tls.Config{
-		MinVersion:   tls.VersionTLS13, // APNs requires at least TLS 1.2, but we enforce 1.3 for better security.
+		MinVersion:   tls.VersionTLS13, // APNs requires at least TLS 1.2, but we enforce 1.3 for better security (see WithMinTLSVersion).
 		Certificates: []tls.Certificate{*cert},
 	}
 	return NewClient(appleapi.ConfigureHTTPClientInitializer(&config), nil, opts...)
 }
 
+// NewClientWithCertFile creates a new APNs client that uses certificate-based
+// authentication with the certificate and private key of the PKCS#12 (.p12)
+// file at path, decrypted with password. It combines certificate.LoadP12File and
+// NewClientWithCert. Load errors wrap their cause, so that a missing file
+// matches fs.ErrNotExist and a wrong password pkcs12.ErrIncorrectPassword.
+func NewClientWithCertFile(path, password string, opts ...appleapi.Option) (*Client, error) {
+	cert, err := certificate.LoadP12File(path, password)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithCert(cert, opts...)
+}
+
 // NewClient creates a new APNs client with a custom HTTP client initializer and token provider.
 // This is an advanced constructor that allows for fine-grained control over the HTTP client.
 // In most cases, `NewClientWithToken` or `NewClientWithCert` should be used instead.
@@ -133,7 +491,44 @@ func NewClient(initializer appleapi.HTTPClientInitializer, tp token.Provider, op
 	if cli.Development {
 		cli.Host = DevelopmentHost
 	}
-	return &Client{inner: cli, TokenBase: tp != nil, TokenLimits: MaxTokens, FastJson: true}, nil
+	c := &Client{inner: cli, TokenBase: tp != nil, TokenLimits: MaxTokens, FastJson: true}
+	c.installCertificateHook()
+	return c, nil
+}
+
+// Close closes the idle connections of the client's HTTP transport and marks
+// the client as closed. Requests sent after Close fail with ErrClientClosed;
+// requests already in flight are not interrupted. Close is safe to call more than once.
+func (cli *Client) Close() error {
+	cli.closed.Store(true)
+	cli.inner.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// HTTPClient returns the HTTP client used to send requests to APNs. It is the
+// client's own, so changes to it, e.g. to its Timeout, affect the client; to
+// wrap its transport, use SetTransport.
+func (cli *Client) HTTPClient() *http.Client {
+	return cli.inner.HTTPClient
+}
+
+// SetTransport replaces the transport of the client's HTTP client with rt,
+// typically a RoundTripper that wraps the current transport, e.g. to trace
+// requests:
+//
+//	cli.SetTransport(otelhttp.NewTransport(cli.HTTPClient().Transport))
+//
+// Authentication is preserved: requests of a token-based client carry their
+// Authorization header when they reach rt. A replacement that does not wrap the
+// current transport loses its TLS configuration, including the client
+// certificate, and the settings of options such as WithMaxConnsPerHost.
+// SetTransport must not be called while requests are in flight.
+func (cli *Client) SetTransport(rt http.RoundTripper) error {
+	if rt == nil {
+		return errors.New("transport cannot be nil")
+	}
+	cli.inner.HTTPClient.Transport = rt
+	return nil
 }
 
 // Push sends a push notification to the APNs.
@@ -145,45 +540,400 @@ func NewClient(initializer appleapi.HTTPClientInitializer, tp token.Provider, op
 // contain some information, such as the APNsID. This can be useful for debugging
 // or preventing duplicate notifications.
 func (cli *Client) Push(ctx context.Context, n *Notification) (*Response, error) {
-	if err := n.Validate(); err != nil {
+	cli.freeze()
+	n, body, err := cli.prepare(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.send(ctx, n, body)
+}
+
+// PushRaw sends a notification with a pre-marshaled JSON body, e.g. rendered
+// by a template engine, in place of the encoded Payload, which may be nil and is
+// ignored. The other fields of the notification are validated and sent as
+// headers as in Push, following SkipValidation and StrictValidation, and the
+// body must be within the size limit of the push type. The body itself is only
+// checked to be valid JSON if ValidateJSON is set.
+func (cli *Client) PushRaw(ctx context.Context, n *Notification, body []byte) (*Response, error) {
+	cli.freeze()
+	n = cli.withDefaults(n)
+	n, err := cli.transform(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := cli.validateHeaders(n); err != nil {
 		return nil, err
 	}
-	if n.Type == notification.Location && !cli.TokenBase {
+	if n.Type == notification.Location && !cli.tokenBased() {
 		return nil, errors.New("location push type is not allowed with certificate-based connection")
 	}
+	if cli.ValidateJSON && !json.Valid(body) {
+		return nil, ErrInvalidJSON
+	}
+	if err := checkPayloadSize(n.Type, len(body)); err != nil {
+		return nil, err
+	}
+
+	return cli.send(ctx, n, body)
+}
+
+// PrepareBody transforms, validates and marshals the notification as Push
+// does and returns the request body, so that the same payload can be sent to
+// many tokens over separate calls with PushWithBody while being marshaled only
+// once. The DeviceToken of n may be empty, in which case the Transformers see
+// a placeholder token.
+func (cli *Client) PrepareBody(n *Notification) ([]byte, error) {
+	cli.freeze()
+	if n.DeviceToken == "" && n.ChannelID == "" {
+		c := *n
+		c.DeviceToken = placeholderDeviceToken
+		n = &c
+	}
+	_, body, err := cli.prepare(n)
+	return body, err
+}
+
+// placeholderDeviceToken stands in for the device token when a body is
+// prepared for a notification without one.
+const placeholderDeviceToken = "00"
+
+// PushWithBody sends the notification with a body returned by PrepareBody for
+// it, instead of marshaling its payload again. The notification is validated
+// and sent as by PushRaw.
+func (cli *Client) PushWithBody(ctx context.Context, n *Notification, body []byte) (*Response, error) {
+	return cli.PushRaw(ctx, n, body)
+}
+
+// withDefaults returns a copy of the notification with the client's
+// DefaultPushType if it has no Type and, if AutoPriority is set, the default
+// priority of its push type if it has none. If neither applies, the
+// notification itself is returned.
+func (cli *Client) withDefaults(n *Notification) *Notification {
+	pushType := n.Type
+	if pushType == "" && cli.DefaultPushType != "" {
+		pushType = cli.DefaultPushType
+	}
+	prio := n.Priority
+	if prio == priority.None && cli.AutoPriority {
+		prio = defaultPriority(pushType)
+	}
+	if pushType == n.Type && prio == n.Priority {
+		return n
+	}
+	c := *n
+	c.Type = pushType
+	c.Priority = prio
+	return &c
+}
+
+// defaultPriority returns the priority AutoPriority selects for the push type:
+// Immediate for alert and voip pushes, Conserve for background pushes, and None,
+// which leaves the choice to APNs, otherwise.
+func defaultPriority(pushType notification.PushType) priority.Priority {
+	switch pushType {
+	case notification.Alert, notification.Voip:
+		return priority.Immediate
+	case notification.Background:
+		return priority.Conserve
+	default:
+		return priority.None
+	}
+}
+
+// prepare transforms and validates the notification and marshals its payload,
+// returning the notification to send together with the request body.
+func (cli *Client) prepare(n *Notification) (*Notification, []byte, error) {
+	n = cli.withDefaults(n)
+	n, err := cli.transform(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cli.validate(n); err != nil {
+		return nil, nil, err
+	}
+	if n.Type == notification.Location && !cli.tokenBased() {
+		return nil, nil, errors.New("location push type is not allowed with certificate-based connection")
+	}
 	body, err := cli.newBody(n)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	return n, body, nil
+}
+
+// validate validates the notification according to the client's StrictValidation
+// and SkipValidation settings.
+func (cli *Client) validate(n *Notification) error {
+	if cli.SkipValidation {
+		return nil
+	}
+	if cli.StrictValidation {
+		maxExpiration := cli.MaxExpiration
+		if maxExpiration <= 0 {
+			maxExpiration = DefaultMaxExpiration
+		}
+		return n.validateStrict(maxExpiration)
+	}
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if cli.MaxExpiration > 0 {
+		return n.ValidateExpiration(cli.MaxExpiration)
 	}
+	return nil
+}
 
+// validateHeaders validates the fields of the notification sent as headers
+// according to the same settings as validate. It is used by PushRaw, which
+// ignores the Payload.
+func (cli *Client) validateHeaders(n *Notification) error {
+	if cli.SkipValidation {
+		return nil
+	}
+	if err := n.validateHeaders(); err != nil {
+		return err
+	}
+	if cli.StrictValidation {
+		maxExpiration := cli.MaxExpiration
+		if maxExpiration <= 0 {
+			maxExpiration = DefaultMaxExpiration
+		}
+		if err := n.ValidateExpiration(maxExpiration); err != nil {
+			return err
+		}
+		return n.validateStrictTopic()
+	}
+	if cli.MaxExpiration > 0 {
+		return n.ValidateExpiration(cli.MaxExpiration)
+	}
+	return nil
+}
+
+// transform applies the client's transformers to a clone of the notification.
+// If no transformers are configured, the notification is returned as is.
+func (cli *Client) transform(n *Notification) (*Notification, error) {
+	if len(cli.Transformers) == 0 {
+		return n, nil
+	}
+	c := n.Clone()
+	for i, transformer := range cli.Transformers {
+		if err := transformer(c); err != nil {
+			return nil, fmt.Errorf("notification transformer %d failed: %w", i, err)
+		}
+	}
+	return c, nil
+}
+
+// send sends the notification with the given body, assigning a generated
+// APNsID if AutoGenerateAPNsID is set and retrying failures up to MaxRetries times.
+func (cli *Client) send(ctx context.Context, n *Notification, body []byte) (*Response, error) {
+	if !cli.AutoGenerateAPNsID || n.APNsID != "" {
+		return cli.sendWithRetry(ctx, func() (*Response, error) {
+			return cli.deliver(ctx, n, body)
+		})
+	}
+	c := *n
+	c.APNsID = uuid.NewString()
+	response, err := cli.sendWithRetry(ctx, func() (*Response, error) {
+		return cli.deliver(ctx, &c, body)
+	})
+	if response != nil {
+		response.APNsID = c.APNsID
+	}
+	return response, err
+}
+
+// deliver sends the notification with the given body, applying the client's
+// per-request timeout and suppressing duplicates within the DedupWindow.
+func (cli *Client) deliver(ctx context.Context, n *Notification, body []byte) (*Response, error) {
+	if cli.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.RequestTimeout)
+		defer cancel()
+	}
+
+	if cli.DedupWindow > 0 && n.APNsID != "" {
+		key := dedupKey(n)
+		if !cli.dedup.reserve(key, time.Now(), cli.DedupWindow) {
+			return &Response{APNsID: n.APNsID, Deduplicated: true}, nil
+		}
+		response, err := cli.sendRequest(ctx, n, body)
+		if err != nil {
+			cli.dedup.release(key)
+		}
+		return response, err
+	}
+	return cli.sendRequest(ctx, n, body)
+}
+
+// sendRequest builds the request for the notification, sends it and handles the response.
+// Every request that reaches the transport is counted in the client's Stats.
+func (cli *Client) sendRequest(ctx context.Context, n *Notification, body []byte) (*Response, error) {
+	n = cli.applyThrottle(n)
 	req, err := cli.newRequest(ctx, n, body)
 	if err != nil {
 		return nil, err
 	}
+	response, err := cli.roundTrip(req)
+	if cli.RetryGoAway && isGoAway(err) && ctx.Err() == nil {
+		// APNs closed the connection to have the client reconnect; send the
+		// request once more on a new connection, regardless of MaxRetries.
+		if req, err = cli.newRequest(ctx, n, body); err != nil {
+			return nil, err
+		}
+		response, err = cli.roundTrip(req)
+	}
+	cli.throttle.record(err)
+	return response, err
+}
 
+// roundTrip sends the request and handles the response, counting it in the
+// client's Stats and reporting it to OnResult.
+func (cli *Client) roundTrip(req *http.Request) (*Response, error) {
+	var response *Response
+	err := cli.track(req, func() (int, error) {
+		var status int
+		var err error
+		response, status, err = cli.exchange(req)
+		return status, err
+	})
+	return response, err
+}
+
+// track sends the request with send, which returns the HTTP status code of the
+// response, or zero if none was received. It applies the limit on requests in
+// flight, logs the request, counts it in the client's Stats and reports it to
+// OnResult.
+func (cli *Client) track(req *http.Request, send func() (int, error)) error {
+	if cli.closed.Load() {
+		return ErrClientClosed
+	}
+	if cli.inFlight != nil {
+		n, err := cli.inFlight.acquire(req.Context(), req.ContentLength)
+		if err != nil {
+			return err
+		}
+		defer cli.inFlight.release(n)
+	}
+	cli.logRequest(req)
+	start := time.Now()
+	status, err := send()
+	latency := time.Since(start)
+	cli.stats.record(err)
+	cli.logFailure(req.Context(), req, status, err, latency)
+	cli.reportResult(req.Header.Get("apns-push-type"), status, err, latency)
+	return err
+}
+
+// exchange sends the request and handles the response. It also returns the
+// HTTP status code of the response, or zero if none was received.
+func (cli *Client) exchange(req *http.Request) (*Response, int, error) {
 	resp, err := cli.do(req)
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return nil, 0, err // nothing was sent
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to send APNs request: %w", err)
+		token, ok := strings.CutPrefix(req.URL.Path, Path)
+		if !ok { // a broadcast has no device token
+			token = ""
+		}
+		return nil, 0, &TransportError{
+			DeviceToken: token,
+			ChannelID:   req.Header.Get("apns-channel-id"),
+			APNsID:      req.Header.Get("apns-id"),
+			PushType:    req.Header.Get("apns-push-type"),
+			Topic:       req.Header.Get("apns-topic"),
+			Err:         err,
+		}
 	}
 	defer resp.Body.Close()
 
-	return cli.handleResponse(resp)
+	if err := cli.checkProtocol(resp); err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	response, err := cli.handleResponse(resp)
+	return response, resp.StatusCode, err
+}
+
+// reportResult calls OnResult, if set, recovering from any panic in it so that
+// a faulty callback cannot break sending.
+func (cli *Client) reportResult(pushType string, status int, err error, latency time.Duration) {
+	if cli.OnResult == nil {
+		return
+	}
+	var reason string
+	var apnsErr *Error
+	if errors.As(err, &apnsErr) {
+		reason = apnsErr.Reason
+	}
+	defer func() { _ = recover() }()
+	cli.OnResult(pushType, status, reason, latency)
+}
+
+// SendRequest sends a request with the given headers and body to the device token,
+// bypassing Notification construction, validation and payload encoding entirely.
+// The client still applies authentication, the base URL and path, and its
+// RequestTimeout. The `authorization` header cannot be set, and a `location`
+// push type is rejected on certificate-based connections as in Push.
+//
+// This is a low-level escape hatch; prefer Push whenever possible.
+func (cli *Client) SendRequest(ctx context.Context, deviceToken string, headers map[string]string, body []byte) (*Response, error) {
+	cli.freeze()
+	if deviceToken == "" {
+		return nil, errors.New("DeviceToken is required")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cli.inner.Host+Path+url.PathEscape(deviceToken), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range headers {
+		if http.CanonicalHeaderKey(name) == "Authorization" {
+			return nil, errors.New("authorization header is managed by the client")
+		}
+		req.Header.Set(name, value)
+	}
+	if notification.PushType(req.Header.Get("apns-push-type")) == notification.Location && !cli.tokenBased() {
+		return nil, errors.New("location push type is not allowed with certificate-based connection")
+	}
+
+	if cli.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.RequestTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	response, err := cli.roundTrip(req)
+	if response != nil {
+		response.DeviceToken = deviceToken
+	}
+	return response, err
 }
 
-func (cli *Client) do(req *http.Request) (*http.Response, error) {
-	if cli.TokenBase {
-		return cli.inner.Do(req) // includes token handling
+// checkProtocol reports an ErrHTTP2Required error if a TLS connection was
+// negotiated with a protocol other than HTTP/2 and the client is not in gateway mode.
+func (cli *Client) checkProtocol(resp *http.Response) error {
+	if cli.Gateway || resp.TLS == nil || resp.ProtoMajor == 2 {
+		return nil
 	}
-	return cli.inner.HTTPClient.Do(req) // certificate based, raw http client
+	return fmt.Errorf("%w: the connection negotiated %s; check for proxies that downgrade the connection or set Client.Gateway", ErrHTTP2Required, resp.Proto)
 }
 
+// handleResponse reads the response and returns it along with the error it
+// carries, if any. A 200 response is a success whatever its body, which APNs
+// usually leaves empty; an empty body is reported as an error only for other
+// status codes.
 func (cli *Client) handleResponse(resp *http.Response) (*Response, error) {
 	response := &Response{
-		APNsID: resp.Header.Get("apns-id"),
+		APNsID:     resp.Header.Get("apns-id"),
+		UniqueID:   resp.Header.Get("apns-unique-id"),
+		StatusCode: resp.StatusCode,
+		ReceivedAt: time.Now(),
 	}
-
-	if cli.inner.Development {
-		response.UniqueID = resp.Header.Get("apns-unique-id")
+	if cli.CaptureHeaders {
+		response.Headers = resp.Header.Clone()
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -192,82 +942,156 @@ func (cli *Client) handleResponse(resp *http.Response) (*Response, error) {
 	}
 
 	if resp.StatusCode == http.StatusOK {
+		if cli.CaptureResponseBody && len(bytes.TrimSpace(body)) > 0 && json.Valid(body) {
+			response.Body = json.RawMessage(body)
+		}
 		return response, nil
 	}
 
+	err = responseError(resp.StatusCode, body)
+	if apnsErr, ok := err.(*Error); ok && cli.CaptureResponseBody {
+		apnsErr.RawBody = body
+	}
+	return response, err
+}
+
+// responseError returns the error for a failed response with the given status
+// code and body: an *Error if the body carries an APNs reason, and a generic
+// error otherwise.
+func responseError(statusCode int, body []byte) error {
 	var errPayload struct {
 		Reason    string `json:"reason"`
 		Timestamp int64  `json:"timestamp,omitempty"`
 	}
 
 	if len(body) == 0 {
-		return response, fmt.Errorf("APNs transport error: empty response body, status=%d", resp.StatusCode)
+		return fmt.Errorf("APNs transport error: empty response body, status=%d", statusCode)
 	}
 	// Check if the response body contains an APNs error reason
 	if err := json.Unmarshal(body, &errPayload); err != nil {
 		// If unmarshalling fails, it's not a structured APNs error,
 		// treat it as a generic HTTP error.
-		return response, fmt.Errorf("APNs request failed with status %d: failed to parse error response: %w", resp.StatusCode, err)
+		return fmt.Errorf("APNs request failed with status %d: failed to parse error response: %w", statusCode, err)
 	}
 
 	// Only return Error if a reason is explicitly provided in the response body.
 	// Otherwise, it's a generic HTTP error or an unknown APNs error without a specific reason.
 	if errPayload.Reason != "" {
-		apnsErr := &Error{
-			StatusCode: resp.StatusCode,
+		return &Error{
+			StatusCode: statusCode,
 			Reason:     errPayload.Reason,
 			Timestamp:  errPayload.Timestamp,
 		}
-		return response, apnsErr
 	}
 
 	// If no specific APNs reason is provided, return a generic error.
-	return response, fmt.Errorf("APNs request failed with status %d", resp.StatusCode)
+	return fmt.Errorf("APNs request failed with status %d", statusCode)
 }
 
 func (cli *Client) newBody(n *Notification) ([]byte, error) {
+	if n.Payload == nil {
+		// Validate reports this too, but it is skipped with SkipValidation.
+		return nil, fmt.Errorf("Payload is required for %s push type", n.Type)
+	}
+	body, err := cli.marshalPayload(n)
+	if err != nil {
+		return nil, err
+	}
+	if cli.ValidateJSON && !json.Valid(body) {
+		return nil, ErrInvalidJSON
+	}
+	if err := checkPayloadSize(n.Type, len(body)); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// marshalPayload marshals the payload of the notification, reusing the body
+// shared by the notifications created from the same template.
+func (cli *Client) marshalPayload(n *Notification) ([]byte, error) {
+	if n.body == nil || n.Payload == nil {
+		return cli.marshal(n.Payload)
+	}
+	return n.body.get(n.Payload, cli.marshal)
+}
+
+// marshal encodes the payload with the client's Marshaler or the encoder
+// selected by FastJson.
+func (cli *Client) marshal(p *Payload) ([]byte, error) {
 	var err error
 	var body []byte
-	if cli.FastJson {
-		body, err = n.Payload.MarshalJSONFast()
+	if cli.Marshaler != nil {
+		body, err = cli.Marshaler.Marshal(p)
 		if err != nil {
 			return nil, fmt.Errorf("fail to marshal json: %w", err)
 		}
-	} else {
-		body, err = json.Marshal(n.Payload)
+	} else if cli.FastJson {
+		body, err = p.MarshalJSONFast()
+		if errors.Is(err, payload.ErrInvalidType) {
+			// The fast encoder supports a limited set of types; let encoding/json
+			// handle the rest instead of failing the push.
+			body, err = json.Marshal(p)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("fail to marshal json: %w", err)
 		}
-	}
-	if n.Type == notification.Voip {
-		if len(body) > 5120 {
-			return nil, fmt.Errorf("payload too large for Voip: %d bytes", len(body))
-		}
 	} else {
-		if len(body) > 4096 {
-			return nil, fmt.Errorf("payload too large: %d bytes", len(body))
+		body, err = json.Marshal(p)
+		if err != nil {
+			return nil, fmt.Errorf("fail to marshal json: %w", err)
 		}
 	}
 	return body, nil
 }
 
+// checkPayloadSize reports an error if a payload of n bytes exceeds the limit
+// that APNs applies to the given push type.
+func checkPayloadSize(pushType notification.PushType, n int) error {
+	if pushType == notification.Voip {
+		if n > MaxVoIPPayloadSize {
+			return fmt.Errorf("payload too large for Voip: %d bytes (max %d)", n, MaxVoIPPayloadSize)
+		}
+		return nil
+	}
+	if n > MaxPayloadSize {
+		return fmt.Errorf("payload too large: %d bytes (max %d)", n, MaxPayloadSize)
+	}
+	return nil
+}
+
 func (cli *Client) newRequest(ctx context.Context, n *Notification, body []byte) (*http.Request, error) {
 	path := cli.inner.Host + Path + url.PathEscape(n.DeviceToken)
+	if n.ChannelID != "" {
+		path = cli.inner.Host + BroadcastPath + url.PathEscape(n.BundleID)
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Validate rejects reserved headers too, but it is skipped with SkipValidation.
+	for name, value := range n.Headers {
+		if isReservedHeader(name) {
+			return nil, fmt.Errorf("header %q is reserved and cannot be set in Headers", name)
+		}
+		req.Header.Set(name, value)
+	}
 	req.Header.Set("apns-push-type", string(n.Type))
 	req.Header.Set("apns-topic", n.Topic())
 
 	if n.APNsID != "" {
 		req.Header.Set("apns-id", n.APNsID)
 	}
+	if n.ChannelID != "" {
+		req.Header.Set("apns-channel-id", n.ChannelID)
+	}
 	if n.Expiration != nil {
 		req.Header.Set("apns-expiration", n.Expiration.String())
 	}
 	if n.Priority != priority.None {
+		if !n.Priority.Valid() {
+			return nil, fmt.Errorf("invalid apns-priority: %d", n.Priority)
+		}
 		req.Header.Set("apns-priority", n.Priority.String())
 	}
 	if n.CollapseID != "" {
@@ -283,52 +1107,59 @@ func (cli *Client) newRequest(ctx context.Context, n *Notification, body []byte)
 // `*MultiError` that contains all failures. If all notifications are sent successfully,
 // the error will be nil.
 //
+// The first token is sent on its own before the others, so that a problem that
+// affects the whole batch (e.g. an invalid credential or topic) is found with a
+// single request. If it fails for a reason that concerns only its device token,
+// such as Unregistered, the failure is reported in the `*MultiError` like any
+// other and the remaining tokens are sent. Otherwise the failure affects the whole
+// batch: the remaining tokens are not sent and its error is returned as is, not
+// as a `*MultiError`, together with only the Deduplicated responses of
+// DedupeTokens, if any. A failed delivery is never reported among the successful
+// responses.
+//
+// The payload is marshaled once for all tokens, unless the client has
+// Transformers: since they may depend on the device token, they are applied for
+// each token, which is then validated and marshaled on its own as by PushBatch,
+// and a failure to prepare a token other than the first is reported in the
+// `*MultiError`.
+//
 // This method is more efficient than calling `Push` in a loop as it utilizes
 // goroutines to send notifications concurrently.
 func (cli *Client) PushMulti(ctx context.Context, n *Notification, tokens []string) ([]*Response, error) {
-	if len(tokens) == 0 {
-		return nil, errors.New("token list is empty")
+	cli.freeze()
+	var deduped []*Response
+	if cli.DedupeTokens {
+		tokens, deduped = dedupeTokens(tokens)
 	}
-	if len(tokens) > cli.TokenLimits {
-		return nil, fmt.Errorf("token limit exceeded: got %d tokens, maximum allowed is %d", len(tokens), cli.TokenLimits)
+	if err := validateBatch(tokens, cli.TokenLimits, n); err != nil {
+		return nil, err
 	}
 	successes := make([]*Response, 0, len(tokens))
+	ctx = cli.withRetryBudget(ctx)
 
+	orig := n
 	firstToken := tokens[0]
-	n.DeviceToken = firstToken
-	if err := n.Validate(); err != nil {
-		return nil, err
-	}
-	if n.Type == notification.Location && !cli.TokenBase {
-		return nil, errors.New("location push type is not allowed with certificate-based connection")
-	}
-
-	body, err := cli.newBody(n)
-	if err != nil {
-		return nil, err
-	}
-	req, err := cli.newRequest(ctx, n, body)
+	first := *n
+	first.DeviceToken = firstToken
+	n, body, err := cli.prepare(&first)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := cli.do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send APNs request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	response, err := cli.handleResponse(resp)
-	if err != nil {
-		return []*Response{response}, err
-	}
-
-	response.DeviceToken = firstToken
-	successes = append(successes, response)
-
 	remaining := tokens[1:]
 	failures := make(map[string]error, len(remaining)/2)
 
+	response, err := cli.sendPaced(ctx, n, body)
+	switch {
+	case err == nil:
+		response.DeviceToken = firstToken
+		successes = append(successes, response)
+	case tokenFailure(err):
+		failures[firstToken] = err
+	default:
+		return append(successes, deduped...), err
+	}
+
 	type result struct {
 		Token string
 		Resp  *Response
@@ -346,21 +1177,24 @@ func (cli *Client) PushMulti(ctx context.Context, n *Notification, tokens []stri
 				return
 			}
 
-			notification := n.Clone()
+			// The body is already marshaled and the copies are only read while
+			// sending, so a shallow copy is enough. Transformers may depend on
+			// the device token, so with Transformers each token is prepared on
+			// its own, as by PushBatch.
+			notification := *n
 			notification.DeviceToken = token
-
-			req, err := cli.newRequest(ctx, notification, body)
-			if err != nil {
-				results <- result{Token: token, Err: err}
-				return
+			tn, tbody := &notification, body
+			if len(cli.Transformers) > 0 {
+				c := *orig
+				c.DeviceToken = token
+				var err error
+				if tn, tbody, err = cli.prepare(&c); err != nil {
+					results <- result{Token: token, Err: err}
+					return
+				}
 			}
-			resp, err := cli.do(req)
-			if err != nil {
-				results <- result{Token: token, Err: err}
-				return
-			}
-			defer resp.Body.Close()
-			response, err := cli.handleResponse(resp)
+
+			response, err := cli.sendPaced(ctx, tn, tbody)
 			results <- result{Token: token, Resp: response, Err: err}
 		}(token)
 	}
@@ -376,6 +1210,7 @@ func (cli *Client) PushMulti(ctx context.Context, n *Notification, tokens []stri
 			successes = append(successes, response)
 		}
 	}
+	successes = append(successes, deduped...)
 
 	if len(failures) > 0 {
 		return successes, &MultiError{Failures: failures}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -242,3 +243,129 @@ func BenchmarkClient_PushMulti(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkPrepareBatch compares validating and marshaling a batch of 1000
+// distinct payloads serially with doing so concurrently, as PushBatch does.
+func BenchmarkPrepareBatch(b *testing.B) {
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "benchmark-token"})
+	if err != nil {
+		b.Fatalf("NewClient failed: %v", err)
+	}
+
+	items := make([]BatchItem, 1000)
+	for i := range items {
+		items[i] = BatchItem{
+			Token: fmt.Sprintf("device-token-%d", i),
+			Notification: &Notification{
+				BundleID: "com.example.benchmark",
+				Type:     notification.Alert,
+				Payload: &Payload{
+					APS: payload.APS{
+						Alert: payload.Alert{Title: "Game Request", Body: fmt.Sprintf("Player %d wants to play", i)},
+						Badge: i,
+						Sound: "default",
+					},
+					CustomData: map[string]any{"game_id": fmt.Sprintf("game-%d", i), "level": i % 50},
+				},
+			},
+		}
+	}
+
+	for name, workers := range map[string]int{"Serial": 1, "Concurrent": runtime.GOMAXPROCS(0)} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				for p := range client.prepareBatch(context.Background(), items, workers) {
+					if p.err != nil {
+						b.Fatalf("prepare failed: %v", p.err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkClient_Push_WithToken(b *testing.B) {
+	transport := respondWith(http.StatusOK, "")
+	client := newTestClient(b, transport)
+	template := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  benchmarkPayloads["FullAlert"],
+	}
+
+	b.Run("Template", func(b *testing.B) {
+		b.ReportAllocs()
+		shared := template.Template()
+		for i := 0; i < b.N; i++ {
+			if _, err := client.Push(context.Background(), shared.WithToken("test-device-token")); err != nil {
+				b.Fatalf("Push failed: %v", err)
+			}
+		}
+	})
+	b.Run("Copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			n := *template
+			n.DeviceToken = "test-device-token"
+			if _, err := client.Push(context.Background(), &n); err != nil {
+				b.Fatalf("Push failed: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkClient_PushWithBody(b *testing.B) {
+	transport := respondWith(http.StatusOK, "")
+	client := newTestClient(b, transport)
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     benchmarkPayloads["FullAlert"],
+	}
+
+	b.Run("PushWithBody", func(b *testing.B) {
+		body, err := client.PrepareBody(n)
+		if err != nil {
+			b.Fatalf("PrepareBody failed: %v", err)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := client.PushWithBody(context.Background(), n, body); err != nil {
+				b.Fatalf("PushWithBody failed: %v", err)
+			}
+		}
+	})
+	b.Run("Push", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := client.Push(context.Background(), n); err != nil {
+				b.Fatalf("Push failed: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkClient_Push_SkipValidation(b *testing.B) {
+	transport := respondWith(http.StatusOK, "")
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     benchmarkPayloads["FullAlert"],
+	}
+
+	for _, skip := range []bool{false, true} {
+		b.Run(fmt.Sprintf("SkipValidation=%t", skip), func(b *testing.B) {
+			client := newTestClient(b, transport)
+			client.SkipValidation = skip
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.Push(context.Background(), n); err != nil {
+					b.Fatalf("Push failed: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package apns
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
@@ -11,21 +12,27 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp" // Import go-cmp
+	"github.com/google/uuid"
 	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/notification/priority"
 	"github.com/takimoto3/apns/payload" // Import the payload package
 	"github.com/takimoto3/appleapi-core"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 // MockTokenProvider is a mock implementation of token.Provider
@@ -38,6 +45,42 @@ func (m *MockTokenProvider) GetToken(t time.Time) (string, error) { // Corrected
 	return m.Token, m.Err
 }
 
+// roundTripFunc adapts a function to the http.RoundTripper interface.
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newTestClient returns a token-based client that sends its requests through
+// transport instead of the network.
+func newTestClient(tb testing.TB, transport http.RoundTripper, opts ...appleapi.Option) *Client {
+	tb.Helper()
+	opts = append([]appleapi.Option{appleapi.WithTransport(transport)}, opts...)
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"}, opts...)
+	if err != nil {
+		tb.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+// respondWith returns a transport that answers every request with the status
+// code and body.
+func respondWith(status int, body string) roundTripFunc {
+	return func(*http.Request) (*http.Response, error) {
+		return newResponse(status, body), nil
+	}
+}
+
+// newResponse returns a response with the status code and body, and no headers.
+func newResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
 type mockRoundTripper struct {
 	resp *http.Response
 }
@@ -130,6 +173,381 @@ func TestNewClientWithCert(t *testing.T) {
 	}
 }
 
+func TestNewClientWithCertFile(t *testing.T) {
+	dir := t.TempDir()
+	cert := createCert(t)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	data, err := pkcs12.Modern.Encode(cert.PrivateKey, leaf, nil, "secret")
+	if err != nil {
+		t.Fatalf("failed to encode p12: %v", err)
+	}
+	validPath := filepath.Join(dir, "valid.p12")
+	invalidPath := filepath.Join(dir, "invalid.p12")
+	if err := os.WriteFile(validPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalidPath, []byte("not a p12 file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		client, err := NewClientWithCertFile(validPath, "secret", appleapi.WithDevelopment())
+		if err != nil {
+			t.Fatalf("NewClientWithCertFile failed: %v", err)
+		}
+		if client.TokenBase || client.inner.Host != DevelopmentHost {
+			t.Errorf("TokenBase = %t, Host = %q; want a certificate-based development client", client.TokenBase, client.inner.Host)
+		}
+		got := client.cert.Load()
+		if got == nil || !bytes.Equal(got.Certificate[0], cert.Certificate[0]) {
+			t.Error("Expected the client to use the certificate of the file")
+		}
+	})
+
+	tests := map[string]struct {
+		path, password string
+		wantErr        error
+		wantMsg        string
+	}{
+		"file not found": {
+			path:     filepath.Join(dir, "missing.p12"),
+			password: "secret",
+			wantErr:  fs.ErrNotExist,
+			wantMsg:  "failed to read p12 file",
+		},
+		"wrong password": {
+			path:     validPath,
+			password: "wrong",
+			wantErr:  pkcs12.ErrIncorrectPassword,
+			wantMsg:  "failed to decode p12 file",
+		},
+		"invalid format": {
+			path:     invalidPath,
+			password: "secret",
+			wantMsg:  "failed to decode p12 file",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClientWithCertFile(tt.path, tt.password)
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if client != nil {
+				t.Error("Expected no client on error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected the error to match %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Expected the error to contain %q, got %q", tt.wantMsg, err)
+			}
+		})
+	}
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	tests := map[string]struct {
+		version uint16
+		want    uint16
+		wantErr string
+	}{
+		"default": {
+			want: tls.VersionTLS13,
+		},
+		"TLS 1.2": {
+			version: tls.VersionTLS12,
+			want:    tls.VersionTLS12,
+		},
+		"TLS 1.3": {
+			version: tls.VersionTLS13,
+			want:    tls.VersionTLS13,
+		},
+		"TLS 1.1 rejected": {
+			version: tls.VersionTLS11,
+			want:    tls.VersionTLS13,
+			wantErr: "unsupported minimum TLS version",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClientWithCert(createCert(t))
+			if err != nil {
+				t.Fatalf("NewClientWithCert failed: %v", err)
+			}
+			if tt.version != 0 {
+				err = client.Configure(WithMinTLSVersion(tt.version))
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Configure() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Configure() unexpected error: %v", err)
+			}
+
+			tr := client.inner.HTTPClient.Transport.(*http.Transport)
+			if got := tr.TLSClientConfig.MinVersion; got != tt.want {
+				t.Errorf("MinVersion = %#04x, want %#04x", got, tt.want)
+			}
+			if len(tr.TLSClientConfig.Certificates) == 0 {
+				t.Error("certificate must be kept")
+			}
+		})
+	}
+}
+
+func TestWithRootCAs(t *testing.T) {
+	client, err := NewClientWithCert(createCert(t))
+	if err != nil {
+		t.Fatalf("NewClientWithCert failed: %v", err)
+	}
+	if err := client.Configure(WithRootCAs(nil)); err == nil {
+		t.Error("WithRootCAs(nil) succeeded, want error")
+	}
+	pool := x509.NewCertPool()
+	if err := client.Configure(WithRootCAs(pool)); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	tr := client.inner.HTTPClient.Transport.(*http.Transport)
+	if tr.TLSClientConfig.RootCAs != pool {
+		t.Error("RootCAs not set")
+	}
+	if tr.TLSClientConfig.MinVersion != tls.VersionTLS13 || tr.TLSClientConfig.GetClientCertificate == nil {
+		t.Error("TLS configuration must be kept")
+	}
+}
+
+func TestWithKeepAlive(t *testing.T) {
+	initializers := map[string]appleapi.HTTPClientInitializer{
+		"default":   appleapi.DefaultHTTPClientInitializer(),
+		"configure": appleapi.ConfigureHTTPClientInitializer(&appleapi.HTTPConfig{}),
+	}
+	for name, initializer := range initializers {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(initializer, &MockTokenProvider{Token: "test-token"})
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			if err := client.Configure(WithKeepAlive(30*time.Second, 5*time.Second)); err != nil {
+				t.Fatalf("Configure() unexpected error: %v", err)
+			}
+			tr := client.inner.HTTPClient.Transport.(*http.Transport)
+			if tr.HTTP2 == nil {
+				t.Fatal("HTTP2 config not set")
+			}
+			if tr.HTTP2.SendPingTimeout != 30*time.Second || tr.HTTP2.PingTimeout != 5*time.Second {
+				t.Errorf("HTTP2 config = {SendPingTimeout:%v PingTimeout:%v}, want {30s 5s}", tr.HTTP2.SendPingTimeout, tr.HTTP2.PingTimeout)
+			}
+		})
+	}
+
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	for _, tt := range []struct{ readIdle, pingTimeout time.Duration }{{0, time.Second}, {time.Second, -1}} {
+		if err := client.Configure(WithKeepAlive(tt.readIdle, tt.pingTimeout)); err == nil {
+			t.Errorf("WithKeepAlive(%v, %v): expected an error", tt.readIdle, tt.pingTimeout)
+		}
+	}
+}
+
+func TestWithConnectionPool(t *testing.T) {
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	err = client.Configure(WithMaxConnsPerHost(8), WithMaxIdleConns(500), WithIdleConnTimeout(5*time.Minute))
+	if err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	tr := client.inner.HTTPClient.Transport.(*http.Transport)
+	if tr.MaxConnsPerHost != 8 {
+		t.Errorf("MaxConnsPerHost = %d, want 8", tr.MaxConnsPerHost)
+	}
+	if tr.MaxIdleConnsPerHost != 500 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want 500", tr.MaxIdleConnsPerHost)
+	}
+	if tr.MaxIdleConns < 500 {
+		t.Errorf("MaxIdleConns = %d, want at least 500", tr.MaxIdleConns)
+	}
+	if tr.IdleConnTimeout != 5*time.Minute {
+		t.Errorf("IdleConnTimeout = %v, want 5m", tr.IdleConnTimeout)
+	}
+
+	for name, opt := range map[string]Option{
+		"negative max conns":      WithMaxConnsPerHost(-1),
+		"negative max idle conns": WithMaxIdleConns(-1),
+		"negative idle timeout":   WithIdleConnTimeout(-time.Second),
+	} {
+		if err := client.Configure(opt); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	custom, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"},
+		appleapi.WithTransport(roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("unused") })))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := custom.Configure(WithMaxIdleConns(10)); err == nil || !strings.Contains(err.Error(), "cannot configure") {
+		t.Errorf("Configure() on a custom transport error = %v, want a cannot configure error", err)
+	}
+}
+
+func TestWithPort2197(t *testing.T) {
+	tests := map[string]struct {
+		newClient func() (*Client, error)
+		want      string
+	}{
+		"token production": {
+			newClient: func() (*Client, error) {
+				return NewClientWithToken(&MockTokenProvider{Token: "test-token"})
+			},
+			want: "https://api.push.apple.com:2197",
+		},
+		"token development": {
+			newClient: func() (*Client, error) {
+				return NewClientWithToken(&MockTokenProvider{Token: "test-token"}, appleapi.WithDevelopment())
+			},
+			want: "https://api.sandbox.push.apple.com:2197",
+		},
+		"certificate production": {
+			newClient: func() (*Client, error) {
+				return NewClientWithCert(createCert(t))
+			},
+			want: "https://api.push.apple.com:2197",
+		},
+		"certificate development": {
+			newClient: func() (*Client, error) {
+				return NewClientWithCert(createCert(t), appleapi.WithDevelopment())
+			},
+			want: "https://api.sandbox.push.apple.com:2197",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := tt.newClient()
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			if err := client.Configure(WithPort2197()); err != nil {
+				t.Fatalf("Configure() unexpected error: %v", err)
+			}
+			if client.inner.Host != tt.want {
+				t.Errorf("Host = %q, want %q", client.inner.Host, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTokenLimits(t *testing.T) {
+	client, err := NewClientWithToken(&MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	for _, n := range []int{0, -1} {
+		if err := client.Configure(WithTokenLimits(n)); err == nil {
+			t.Errorf("WithTokenLimits(%d) succeeded, want error", n)
+		}
+	}
+	if err := client.Configure(WithTokenLimits(50), WithFastJSON(false)); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	if client.TokenLimits != 50 || client.FastJson {
+		t.Errorf("TokenLimits = %d, FastJson = %t; want 50, false", client.TokenLimits, client.FastJson)
+	}
+
+	// Once the client is in use, its options can no longer be changed.
+	n := &Notification{BundleID: "com.example.app", Type: notification.Alert, Payload: &Payload{APS: payload.APS{Alert: "test"}}}
+	if _, err := client.PrepareBody(n); err != nil {
+		t.Fatalf("PrepareBody failed: %v", err)
+	}
+	if err := client.Configure(WithTokenLimits(10)); !errors.Is(err, ErrClientInUse) {
+		t.Errorf("Configure() on a client in use error = %v, want ErrClientInUse", err)
+	}
+	if client.TokenLimits != 50 {
+		t.Errorf("TokenLimits = %d, want 50", client.TokenLimits)
+	}
+}
+
+func TestWithHost(t *testing.T) {
+	var gotURL string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotURL = r.URL.String()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport, appleapi.WithDevelopment())
+
+	for _, host := range []string{"", "localhost:8443", "ftp://localhost", "https://"} {
+		if err := client.Configure(WithHost(host)); err == nil {
+			t.Errorf("WithHost(%q) succeeded, want error", host)
+		}
+	}
+	if client.inner.Host != DevelopmentHost {
+		t.Fatalf("Host changed by invalid options: %q", client.inner.Host)
+	}
+
+	if err := client.Configure(WithHost("http://localhost:8080/")); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	req, err := client.newRequest(context.Background(), n, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("newRequest failed: %v", err)
+	}
+	if want := "http://localhost:8080/3/device/test-device-token"; req.URL.String() != want {
+		t.Errorf("request URL = %q, want %q", req.URL.String(), want)
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if want := "http://localhost:8080/3/device/test-device-token"; gotURL != want {
+		t.Errorf("sent URL = %q, want %q", gotURL, want)
+	}
+
+	// The host set with Configure takes precedence over the environment
+	// selected by the constructors.
+	constructors := map[string]func() (*Client, error){
+		"token": func() (*Client, error) {
+			return NewClientWithToken(&MockTokenProvider{Token: "test-token"}, appleapi.WithDevelopment())
+		},
+		"certificate": func() (*Client, error) {
+			return NewClientWithCert(createCert(t), appleapi.WithDevelopment())
+		},
+		"custom": func() (*Client, error) {
+			return NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"}, appleapi.WithDevelopment())
+		},
+	}
+	for name, newClient := range constructors {
+		client, err := newClient()
+		if err != nil {
+			t.Fatalf("%s: failed to create client: %v", name, err)
+		}
+		if err := client.Configure(WithHost("https://localhost:8443")); err != nil {
+			t.Fatalf("%s: Configure() unexpected error: %v", name, err)
+		}
+		req, err := client.newRequest(context.Background(), n, []byte(`{}`))
+		if err != nil {
+			t.Fatalf("%s: newRequest failed: %v", name, err)
+		}
+		if want := "https://localhost:8443/3/device/test-device-token"; req.URL.String() != want {
+			t.Errorf("%s: request URL = %q, want %q", name, req.URL.String(), want)
+		}
+	}
+}
+
 func TestClient_Push(t *testing.T) {
 	now := time.Now().Add(time.Hour)
 	expectedToken := "Bearer test-token"
@@ -232,6 +650,9 @@ func TestClient_Push(t *testing.T) {
 	if res.UniqueID != "" { // Not set in mock server
 		t.Errorf("Expected UniqueID to be empty, got %s", res.UniqueID)
 	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected StatusCode %d, got %d", http.StatusOK, res.StatusCode)
+	}
 }
 
 func TestCertificateBaseClient_Push(t *testing.T) {
@@ -335,6 +756,9 @@ func TestCertificateBaseClient_Push(t *testing.T) {
 	if res.UniqueID != "" { // Not set in mock server
 		t.Errorf("Expected UniqueID to be empty, got %s", res.UniqueID)
 	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected StatusCode %d, got %d", http.StatusOK, res.StatusCode)
+	}
 
 }
 
@@ -422,207 +846,238 @@ func TestClient_Push_Error(t *testing.T) {
 	}
 }
 
-func TestClient_Push_ServerError(t *testing.T) {
+func TestClient_Push_UniqueIDInProduction(t *testing.T) {
+	uniqueID := "a9b8c7d6-e5f4-4321-b0a9-876543210fed"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("apns-id", "123e4567-e89b-12d3-a456-4266554400a0")
+		w.Header().Set("apns-unique-id", uniqueID)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "dummy-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if client.inner.Development {
+		t.Fatal("expected a production client")
+	}
+	client.inner.Host = server.URL
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	res, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if res.UniqueID != uniqueID {
+		t.Errorf("Expected UniqueID %s, got %s", uniqueID, res.UniqueID)
+	}
+}
+
+func TestClient_Push_CustomHeaders(t *testing.T) {
+	var got http.Header
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Header.Clone()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+		Headers:     map[string]string{"X-Request-ID": "req-123", "traceparent": "00-abc-def-01"},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if v := got.Get("X-Request-ID"); v != "req-123" {
+		t.Errorf("Expected X-Request-ID %q, got %q", "req-123", v)
+	}
+	if v := got.Get("Traceparent"); v != "00-abc-def-01" {
+		t.Errorf("Expected traceparent %q, got %q", "00-abc-def-01", v)
+	}
+	if v := got.Get("apns-topic"); v != "com.example.app" {
+		t.Errorf("Expected apns-topic %q, got %q", "com.example.app", v)
+	}
+
+	n.Headers = map[string]string{"apns-topic": "com.other.app"}
+	if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("Expected a reserved header error, got %v", err)
+	}
+
+	// Reserved headers are rejected even when validation is skipped.
+	client.SkipValidation = true
+	for _, name := range []string{"apns-priority", "apns-expiration", "apns-collapse-id", "Host", "Content-Length"} {
+		got = nil
+		n.Headers = map[string]string{name: "1"}
+		if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("%s: expected a reserved header error with SkipValidation, got %v", name, err)
+		}
+		if got != nil {
+			t.Errorf("%s: expected no request to be sent", name)
+		}
+	}
+}
+
+func TestClient_Push_CaptureResponseBody(t *testing.T) {
 	testCases := map[string]struct {
-		statusCode int
-		reason     string
-		apnsID     string
-		wantErr    string // Expected error string (APNsReason, or part of a generic error message)
+		capture  bool
+		body     string
+		wantBody string
 	}{
-		"BadRequest_BadDeviceToken": {
-			statusCode: http.StatusBadRequest,
-			reason:     "BadDeviceToken",
-			apnsID:     "error-apns-id-1",
-			wantErr:    "BadDeviceToken",
-		},
-		"Forbidden_MissingProviderToken": {
-			statusCode: http.StatusForbidden,
-			reason:     "MissingProviderToken",
-			apnsID:     "error-apns-id-2",
-			wantErr:    "MissingProviderToken",
+		"JSON body captured": {
+			capture:  true,
+			body:     `{"warning":"deprecated topic"}`,
+			wantBody: `{"warning":"deprecated topic"}`,
 		},
-		"Forbidden_ExpiredProviderToken": {
-			statusCode: http.StatusForbidden,
-			reason:     "ExpiredProviderToken",
-			apnsID:     "error-apns-id-3",
-			wantErr:    "ExpiredProviderToken",
+		"JSON body ignored when disabled": {
+			capture:  false,
+			body:     `{"warning":"deprecated topic"}`,
+			wantBody: "",
 		},
-		"Forbidden_InvalidProviderToken": {
-			statusCode: http.StatusForbidden,
-			reason:     "InvalidProviderToken",
-			apnsID:     "error-apns-id-4",
-			wantErr:    "InvalidProviderToken",
+		"Non-JSON body ignored": {
+			capture:  true,
+			body:     "ok",
+			wantBody: "",
 		},
-		"Forbidden_Forbidden": {
-			statusCode: http.StatusForbidden,
-			reason:     "Forbidden",
-			apnsID:     "error-apns-id-5",
-			wantErr:    "Forbidden",
-		},
-		"BadRequest_BadPath": {
-			statusCode: http.StatusBadRequest,
-			reason:     "BadPath",
-			apnsID:     "error-apns-id-6",
-			wantErr:    "BadPath",
-		},
-		"BadRequest_BadMessageId": {
-			statusCode: http.StatusBadRequest,
-			reason:     "BadMessageId",
-			apnsID:     "error-apns-id-7",
-			wantErr:    "BadMessageId",
-		},
-		"BadRequest_MissingTopic": {
-			statusCode: http.StatusBadRequest,
-			reason:     "MissingTopic",
-			apnsID:     "error-apns-id-8",
-			wantErr:    "MissingTopic",
-		},
-		"BadRequest_TopicDiscrepancy": {
-			statusCode: http.StatusBadRequest,
-			reason:     "TopicDiscrepancy",
-			apnsID:     "error-apns-id-9",
-			wantErr:    "TopicDiscrepancy",
-		},
-		"BadRequest_DeviceTokenNotForTopic": {
-			statusCode: http.StatusBadRequest,
-			reason:     "DeviceTokenNotForTopic",
-			apnsID:     "error-apns-id-10",
-			wantErr:    "DeviceTokenNotForTopic",
-		},
-		"Gone_Unregistered": {
-			statusCode: http.StatusGone,
-			reason:     "Unregistered",
-			apnsID:     "error-apns-id-11",
-			wantErr:    "Unregistered",
-		},
-		"PayloadTooLarge": {
-			statusCode: http.StatusRequestEntityTooLarge,
-			reason:     "PayloadTooLarge",
-			apnsID:     "error-apns-id-12",
-			wantErr:    "PayloadTooLarge",
-		},
-		"TooManyRequests_TooManyProviderTokenUpdates": {
-			statusCode: http.StatusTooManyRequests,
-			reason:     "TooManyProviderTokenUpdates",
-			apnsID:     "error-apns-id-13",
-			wantErr:    "TooManyProviderTokenUpdates",
-		},
-		"TooManyRequests_TooManyRequests": {
-			statusCode: http.StatusTooManyRequests,
-			reason:     "TooManyRequests",
-			apnsID:     "error-apns-id-14",
-			wantErr:    "TooManyRequests",
-		},
-		"InternalServerError": {
-			statusCode: http.StatusInternalServerError,
-			reason:     "InternalServerError",
-			apnsID:     "error-apns-id-15",
-			wantErr:    "InternalServerError",
-		},
-		"ServiceUnavailable": {
-			statusCode: http.StatusServiceUnavailable,
-			reason:     "ServiceUnavailable",
-			apnsID:     "error-apns-id-16",
-			wantErr:    "ServiceUnavailable",
-		},
-		"UnknownError_501": { // e.g., 501 Not Implemented, when no APNs reason is provided
-			statusCode: http.StatusNotImplemented,
-			reason:     "", // Reason is empty for non-APNs errors or unknown ones
-			apnsID:     "error-apns-id-17",
-			wantErr:    fmt.Sprintf("APNs request failed with status %d", http.StatusNotImplemented),
-		},
-		"Success_ButNotError": { // Case where 200 OK does not result in an Error
-			statusCode: http.StatusOK,
-			reason:     "",
-			apnsID:     "success-apns-id",
-			wantErr:    "", // Expect no error
+		"Empty body": {
+			capture:  true,
+			body:     "",
+			wantBody: "",
 		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("apns-id", tc.apnsID)
-				w.WriteHeader(tc.statusCode)
-				if tc.reason != "" {
-					_, _ = w.Write([]byte(fmt.Sprintf(`{"reason": "%s"}`, tc.reason)))
-				} else if tc.statusCode != http.StatusOK {
-					_, _ = w.Write([]byte(`{"message": "Generic error"}`))
-				}
-			}))
-			defer server.Close()
-
-			mockInitializer := appleapi.DefaultHTTPClientInitializer()
-			tp := &MockTokenProvider{Token: "dummy-token"}
-
-			client, err := NewClient(mockInitializer, tp)
-			if err != nil {
-				t.Fatalf("NewClient failed: %v", err)
-			}
-			client.inner.Host = server.URL
+			transport := respondWith(http.StatusOK, tc.body)
+			client := newTestClient(t, transport)
+			client.CaptureResponseBody = tc.capture
 
 			n := &Notification{
 				BundleID:    "com.example.app",
-				DeviceToken: "invalid-device-token",
+				DeviceToken: "test-device-token",
 				Type:        notification.Alert,
 				Payload:     &Payload{APS: payload.APS{Alert: "test"}},
 			}
-
-			_, err = client.Push(context.Background(), n)
-			if tc.wantErr == "" {
-				if err != nil {
-					t.Errorf("Expected no error, but got: %v", err)
-				}
-				return
-			}
-			if err == nil {
-				t.Fatal("Expected an error, but got nil")
+			res, err := client.Push(context.Background(), n)
+			if err != nil {
+				t.Fatalf("Client.Push failed: %v", err)
 			}
-
-			apnsErr, isError := err.(*Error)
-
-			if tc.reason != "" { // Expect Error if Reason is set
-				if !isError {
-					t.Fatalf("Expected error of type *Error for reason '%s', got %T", tc.reason, err)
-				}
-				if apnsErr.Reason != tc.wantErr {
-					t.Errorf("Expected reason '%s', got '%s'", tc.wantErr, apnsErr.Reason)
-				}
-				if apnsErr.StatusCode != tc.statusCode {
-					t.Errorf("Expected status code %d, got %d", tc.statusCode, apnsErr.StatusCode)
-				}
-			} else { // Expect generic error if Reason is not set
-				if isError {
-					t.Fatalf("Did not expect error of type *Error for status %d, but got one: %v", tc.statusCode, apnsErr)
-				}
-				if !strings.Contains(err.Error(), tc.wantErr) {
-					t.Errorf("Expected error message to contain '%s', but got: %v", tc.wantErr, err)
-				}
+			if string(res.Body) != tc.wantBody {
+				t.Errorf("Expected Body %q, got %q", tc.wantBody, string(res.Body))
 			}
 		})
 	}
 }
 
-func TestClient_Push_WithTimeout(t *testing.T) {
-	// Reverted to httptest.NewServer approach due to synctest limitations with net/http's internal I/O blocking.
-	// synctest is powerful for CPU-bound concurrency and deterministic time advancement with durably blocked goroutines,
-	// but it struggles with real (or simulated via net.Pipe) network I/O which is not considered "durably blocked".
+func TestClient_Push_CaptureErrorBody(t *testing.T) {
+	const body = `{"reason":"BadDeviceToken","detail":"unexpected field from a proxy"}`
+	for _, capture := range []bool{true, false} {
+		transport := respondWith(http.StatusBadRequest, body)
+		client := newTestClient(t, transport)
+		client.CaptureResponseBody = capture
+
+		n := &Notification{
+			BundleID:    "com.example.app",
+			DeviceToken: "test-device-token",
+			Type:        notification.Alert,
+			Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+		}
+		_, err := client.Push(context.Background(), n)
+		var apnsErr *Error
+		if !errors.As(err, &apnsErr) {
+			t.Fatalf("capture=%v: expected *Error, got %v", capture, err)
+		}
+		want := ""
+		if capture {
+			want = body
+		}
+		if string(apnsErr.RawBody) != want {
+			t.Errorf("capture=%v: RawBody = %q, want %q", capture, apnsErr.RawBody, want)
+		}
+	}
+}
+
+func TestClient_Push_CaptureHeaders(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(100 * time.Millisecond) // Simulate a slow response
-		w.WriteHeader(http.StatusOK)
+		w.Header().Set("apns-id", "123e4567-e89b-12d3-a456-4266554400a0")
+		w.Header().Set("X-Apple-Diagnostic", "edge-7")
+		if r.URL.Path == Path+"bad-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		}
 	}))
 	defer server.Close()
 
-	// Mock initializer for NewClient
-	mockInitializer := appleapi.DefaultHTTPClientInitializer()
-	tp := &MockTokenProvider{Token: "dummy-token"}
+	for _, capture := range []bool{true, false} {
+		client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		client.inner.Host = server.URL
+		client.CaptureHeaders = capture
 
-	client, err := NewClient(mockInitializer, tp)
-	if err != nil {
-		t.Fatalf("NewClient failed: %v", err)
+		for _, token := range []string{"good-token", "bad-token"} {
+			n := &Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: token,
+				Type:        notification.Alert,
+				Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+			}
+			res, _ := client.Push(context.Background(), n)
+			if res == nil {
+				t.Fatalf("capture=%v, %s: expected a response", capture, token)
+			}
+			if !capture {
+				if res.Headers != nil {
+					t.Errorf("capture=%v, %s: Headers = %v, want nil", capture, token, res.Headers)
+				}
+				continue
+			}
+			if got := res.Headers.Get("apns-id"); got != res.APNsID || got == "" {
+				t.Errorf("capture=%v, %s: apns-id header = %q, want %q", capture, token, got, res.APNsID)
+			}
+			if got := res.Headers.Get("X-Apple-Diagnostic"); got != "edge-7" {
+				t.Errorf("capture=%v, %s: X-Apple-Diagnostic header = %q, want %q", capture, token, got, "edge-7")
+			}
+		}
 	}
-	client.inner.Host = server.URL                          // Manually set the host for testing
-	client.inner.HTTPClient.Timeout = 50 * time.Millisecond // Manually set the timeout
+}
+
+// recordingMarshaler is a PayloadMarshaler that counts its calls.
+type recordingMarshaler struct {
+	calls int
+}
+
+func (m *recordingMarshaler) Marshal(p *Payload) ([]byte, error) {
+	m.calls++
+	return json.Marshal(p)
+}
+
+// brokenMarshaler is a PayloadMarshaler that produces truncated JSON.
+type brokenMarshaler struct{}
+
+func (brokenMarshaler) Marshal(p *Payload) ([]byte, error) {
+	b, err := json.Marshal(p)
+	return b[:len(b)-1], err
+}
+
+func TestClient_Push_ValidateJSON(t *testing.T) {
+	var hits int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits++
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	client.Marshaler = brokenMarshaler{}
 
 	n := &Notification{
 		BundleID:    "com.example.app",
@@ -630,87 +1085,1005 @@ func TestClient_Push_WithTimeout(t *testing.T) {
 		Type:        notification.Alert,
 		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
 	}
-
-	// Use context with a slightly longer timeout to ensure HTTP client's timeout is tested
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-	defer cancel()
-
-	start := time.Now()
-	_, err = client.Push(ctx, n)
-	duration := time.Since(start)
-
-	if err == nil {
-		t.Fatal("Expected a timeout error, but got nil")
-	}
-	if errors.Is(err, context.DeadlineExceeded) {
-		// ok
-	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
-		// ok
-	} else {
-		t.Fatalf("Expected timeout error, got: %v", err)
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed without ValidateJSON: %v", err)
 	}
 
-	// Check for specific timeout errors
-	if !strings.Contains(err.Error(), "Client.Timeout exceeded") && !strings.Contains(err.Error(), "context deadline exceeded") && !strings.Contains(err.Error(), "timeout") {
-		t.Errorf("Expected a timeout error, but got: %v (type %T)", err, err)
+	client.ValidateJSON = true
+	if _, err := client.Push(context.Background(), n); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected ErrInvalidJSON, got %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected the invalid payload not to be sent, got %d requests", hits)
 	}
 
-	// Assert that the timeout occurred approximately within the expected time frame
-	expectedTimeout := client.inner.HTTPClient.Timeout
-	// Allow some buffer for real-world timing variations
-	if duration < expectedTimeout || duration > expectedTimeout*3 {
-		t.Errorf("Expected timeout duration around %v, but got %v", expectedTimeout, duration)
+	client.Marshaler = nil
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Errorf("Client.Push failed with the default encoder: %v", err)
 	}
 }
 
-func TestError_Error(t *testing.T) {
-	testCases := []struct {
-		name     string
-		apnsErr  *Error
-		expected string
-	}{
-		{
-			name: "Without Timestamp",
-			apnsErr: &Error{
-				StatusCode: 400,
-				Reason:     "BadDeviceToken",
-				Timestamp:  0,
-			},
-			expected: "APNs error: status=400 reason=BadDeviceToken",
-		},
-		{
-			name: "With Timestamp",
-			apnsErr: &Error{
-				StatusCode: 500,
-				Reason:     "InternalServerError",
-				Timestamp:  1678886400000,
-			},
-			expected: "APNs error: status=500 reason=InternalServerError timestamp=1678886400000",
-		},
-	}
+func TestClient_Push_Marshaler(t *testing.T) {
+	var gotBody string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	m := &recordingMarshaler{}
+	client.Marshaler = m
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			if errStr := tc.apnsErr.Error(); errStr != tc.expected {
-				t.Errorf("Error.Error() got = %q, want %q", errStr, tc.expected)
-			}
-		})
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if m.calls != 1 {
+		t.Errorf("Expected Marshal to be called once, got %d", m.calls)
+	}
+	if want := `{"aps":{"alert":"test"}}`; gotBody != want {
+		t.Errorf("Expected body %s, got %s", want, gotBody)
 	}
 }
 
-func createCert(t *testing.T) *tls.Certificate {
-	t.Helper()
+func TestClient_SendRequest(t *testing.T) {
+	var gotReq *http.Request
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotReq = r
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Header().Set("apns-id", "123e4567-e89b-12d3-a456-4266554400a0")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
 
-	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
 	if err != nil {
-		t.Fatalf("failed to generate private key: %v", err)
+		t.Fatalf("NewClient failed: %v", err)
 	}
+	client.inner.Host = server.URL
 
-	tmpl := &x509.Certificate{
-		SerialNumber: big.NewInt(1),
-		Subject: pkix.Name{
-			CommonName: "test.local",
-		},
+	headers := map[string]string{
+		"apns-push-type": "background",
+		"apns-topic":     "com.example.app",
+		"apns-priority":  "5",
+		"x-trace-id":     "trace-1",
+	}
+	body := []byte(`{"aps":{"content-available":1},"raw":true}`)
+	res, err := client.SendRequest(context.Background(), "test-device-token", headers, body)
+	if err != nil {
+		t.Fatalf("SendRequest failed: %v", err)
+	}
+	if res.DeviceToken != "test-device-token" || res.APNsID != "123e4567-e89b-12d3-a456-4266554400a0" {
+		t.Errorf("Unexpected response: %+v", res)
+	}
+	if gotReq.Method != http.MethodPost || gotReq.URL.Path != Path+"test-device-token" {
+		t.Errorf("Unexpected request %s %s", gotReq.Method, gotReq.URL.Path)
+	}
+	for name, want := range headers {
+		if got := gotReq.Header.Get(name); got != want {
+			t.Errorf("Expected header %s=%q, got %q", name, want, got)
+		}
+	}
+	if got := gotReq.Header.Get("Authorization"); got != "Bearer test-token" {
+		t.Errorf("Expected authorization header, got %q", got)
+	}
+	if gotBody != string(body) {
+		t.Errorf("Expected body %s, got %s", body, gotBody)
+	}
+
+	testCases := map[string]struct {
+		token       string
+		headers     map[string]string
+		tokenBase   bool
+		errContains string
+	}{
+		"Missing device token": {
+			token:       "",
+			tokenBase:   true,
+			errContains: "DeviceToken is required",
+		},
+		"Authorization header": {
+			token:       "test-device-token",
+			headers:     map[string]string{"Authorization": "bearer other"},
+			tokenBase:   true,
+			errContains: "authorization header is managed by the client",
+		},
+		"Location with certificate": {
+			token:       "test-device-token",
+			headers:     map[string]string{"apns-push-type": "location"},
+			tokenBase:   false,
+			errContains: "location push type is not allowed",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client.TokenBase = tc.tokenBase
+			defer func() { client.TokenBase = true }()
+			_, err := client.SendRequest(context.Background(), tc.token, tc.headers, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Errorf("Expected error containing %q, got %v", tc.errContains, err)
+			}
+		})
+	}
+}
+
+func TestClient_Push_FastJsonFallback(t *testing.T) {
+	var gotBody []byte
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotBody, _ = io.ReadAll(r.Body)
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	if !client.FastJson {
+		t.Fatal("expected FastJson to be enabled by default")
+	}
+
+	type order struct {
+		ID    int    `json:"id"`
+		State string `json:"state"`
+	}
+	p := &Payload{
+		APS:        payload.APS{Alert: "test"},
+		CustomData: map[string]any{"order": order{ID: 7, State: "shipped"}},
+	}
+	if _, err := p.MarshalJSONFast(); !errors.Is(err, payload.ErrInvalidType) {
+		t.Fatalf("expected MarshalJSONFast to fail with ErrInvalidType, got %v", err)
+	}
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     p,
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+
+	want, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if string(gotBody) != string(want) {
+		t.Errorf("Expected body %s, got %s", want, gotBody)
+	}
+}
+
+func TestClient_Push_AutoGenerateAPNsID(t *testing.T) {
+	var sentIDs []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sentIDs = append(sentIDs, r.Header.Get("apns-id"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Apns-Id": []string{"server-generated-id"}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+	client := newTestClient(t, transport)
+	client.AutoGenerateAPNsID = true
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	res, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if len(sentIDs) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(sentIDs))
+	}
+	id, err := uuid.Parse(sentIDs[0])
+	if err != nil || id.Version() != 4 {
+		t.Fatalf("Expected a UUIDv4 apns-id header, got %q (%v)", sentIDs[0], err)
+	}
+	if res.APNsID != sentIDs[0] {
+		t.Errorf("Expected Response.APNsID %s, got %s", sentIDs[0], res.APNsID)
+	}
+	if n.APNsID != "" {
+		t.Errorf("Expected the caller's notification to be unchanged, got APNsID %s", n.APNsID)
+	}
+
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if sentIDs[1] == sentIDs[0] {
+		t.Error("Expected a new apns-id for each push")
+	}
+
+	n.APNsID = "123e4567-e89b-12d3-a456-4266554400a0"
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if sentIDs[2] != n.APNsID {
+		t.Errorf("Expected the explicit apns-id %s to be sent, got %s", n.APNsID, sentIDs[2])
+	}
+}
+
+func TestClient_Push_AutoGenerateAPNsID_ManualIDTakesPrecedence(t *testing.T) {
+	const manualID = "123e4567-e89b-12d3-a456-4266554400a0"
+	var sentIDs []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sentIDs = append(sentIDs, r.Header.Get("apns-id"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Apns-Id": []string{r.Header.Get("apns-id")}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+	client := newTestClient(t, transport)
+	client.AutoGenerateAPNsID = true
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		APNsID:      manualID,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	res, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if len(sentIDs) != 1 || sentIDs[0] != manualID {
+		t.Errorf("Expected the manual apns-id %s to be sent unchanged, got %v", manualID, sentIDs)
+	}
+	if res.APNsID != manualID {
+		t.Errorf("Expected Response.APNsID %s, got %s", manualID, res.APNsID)
+	}
+	if n.APNsID != manualID {
+		t.Errorf("Expected the caller's APNsID to be unchanged, got %s", n.APNsID)
+	}
+
+	// An APNsID set by a transformer is respected as well.
+	const transformedID = "123e4567-e89b-12d3-a456-4266554400a1"
+	client.Transformers = []func(*Notification) error{func(n *Notification) error {
+		n.APNsID = transformedID
+		return nil
+	}}
+	n.APNsID = ""
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if sentIDs[1] != transformedID {
+		t.Errorf("Expected the transformer's apns-id %s to be sent, got %s", transformedID, sentIDs[1])
+	}
+}
+
+func TestClient_Push_ReceivedAt(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		time.Sleep(5 * time.Millisecond)
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	sentAt := time.Now()
+	res, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	doneAt := time.Now()
+	if res.ReceivedAt.Before(sentAt.Add(5*time.Millisecond)) || res.ReceivedAt.After(doneAt) {
+		t.Errorf("Expected ReceivedAt within [%v, %v], got %v", sentAt.Add(5*time.Millisecond), doneAt, res.ReceivedAt)
+	}
+
+	sentAt = time.Now()
+	responses, err := client.PushMulti(context.Background(), n, []string{"token-a", "token-b", "token-c"})
+	if err != nil {
+		t.Fatalf("Client.PushMulti failed: %v", err)
+	}
+	doneAt = time.Now()
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+	for _, res := range responses {
+		if res.ReceivedAt.Before(sentAt) || res.ReceivedAt.After(doneAt) {
+			t.Errorf("%s: expected ReceivedAt within [%v, %v], got %v", res.DeviceToken, sentAt, doneAt, res.ReceivedAt)
+		}
+	}
+}
+
+func TestClient_Push_TransportError(t *testing.T) {
+	var sentID string
+	errConnReset := errors.New("connection reset by peer")
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sentID = r.Header.Get("apns-id")
+		return nil, errConnReset
+	})
+	client := newTestClient(t, transport)
+	client.AutoGenerateAPNsID = true
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	res, err := client.Push(context.Background(), n)
+	if res != nil {
+		t.Errorf("Expected no response, got %+v", res)
+	}
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("Expected a *TransportError, got %v", err)
+	}
+	if sentID == "" || transportErr.APNsID != sentID {
+		t.Errorf("Expected the generated apns-id %q in the error, got %q", sentID, transportErr.APNsID)
+	}
+	if transportErr.DeviceToken != n.DeviceToken || transportErr.PushType != "alert" || transportErr.Topic != "com.example.app" {
+		t.Errorf("Unexpected request metadata in the error: %+v", transportErr)
+	}
+	if !errors.Is(err, errConnReset) {
+		t.Errorf("Expected the error to wrap the transport error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failed to send APNs request: ") {
+		t.Errorf("Unexpected error message: %s", err)
+	}
+}
+
+func TestClient_Push_StrictValidation(t *testing.T) {
+	var hits int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits++
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{ContentAvailable: 1}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed without strict validation: %v", err)
+	}
+
+	client.StrictValidation = true
+	_, err := client.Push(context.Background(), n)
+	var verr *payload.ValidationError
+	if !errors.As(err, &verr) || verr.Code != payload.CodeBackgroundPushType {
+		t.Errorf("Expected a %s validation error, got %v", payload.CodeBackgroundPushType, err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected 1 request, got %d", hits)
+	}
+}
+
+func TestClient_Push_SkipValidation(t *testing.T) {
+	var hits int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits++
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	// An empty APS dictionary fails Validate.
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{},
+	}
+	if _, err := client.Push(context.Background(), n); err == nil {
+		t.Fatal("Expected a validation error without SkipValidation")
+	}
+	if hits != 0 {
+		t.Fatalf("Expected no request for an invalid notification, got %d", hits)
+	}
+
+	client.SkipValidation = true
+	client.StrictValidation = true
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed with SkipValidation: %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected 1 request, got %d", hits)
+	}
+
+	// The payload size is still checked.
+	n.Payload = &Payload{APS: payload.APS{Alert: strings.Repeat("a", 5000)}}
+	if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "payload too large") {
+		t.Errorf("Expected a payload size error, got %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected no request for an oversized payload, got %d requests", hits)
+	}
+
+	// So is the presence of the payload, which cannot be marshaled without one.
+	for _, pushType := range []notification.PushType{notification.Alert, notification.Background, notification.Liveactivity, notification.Voip} {
+		n := &Notification{BundleID: "com.example.app", DeviceToken: "test-device-token", Type: pushType}
+		if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "Payload is required") {
+			t.Errorf("%s: expected a missing payload error, got %v", pushType, err)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("Expected no request without a payload, got %d requests", hits)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	closed := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			close(closed)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.inner.Host = server.URL
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the idle connection to be closed")
+	}
+
+	if _, err := client.Push(context.Background(), n); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+}
+
+func TestClient_Push_MaxExpiration(t *testing.T) {
+	transport := respondWith(http.StatusOK, "")
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Expiration:  notification.NewEpochTime(time.Now().Add(2 * time.Hour)),
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+
+	client.MaxExpiration = time.Hour
+	_, err := client.Push(context.Background(), n)
+	var verr *payload.ValidationError
+	if !errors.As(err, &verr) || verr.Code != payload.CodeExpirationTooFar {
+		t.Errorf("Expected a %s validation error, got %v", payload.CodeExpirationTooFar, err)
+	}
+}
+
+func TestClient_Push_DefaultPushType(t *testing.T) {
+	var pushTypes []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		pushTypes = append(pushTypes, r.Header.Get("apns-push-type"))
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "apns-push-type is required") {
+		t.Fatalf("Expected a missing push type error without a default, got %v", err)
+	}
+
+	client.DefaultPushType = notification.Alert
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if n.Type != "" {
+		t.Errorf("Expected the notification not to be modified, got Type %q", n.Type)
+	}
+
+	n.Type = notification.Background
+	n.Payload = &Payload{APS: payload.APS{ContentAvailable: 1}}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+
+	n.Type = "bogus"
+	if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "invalid apns-push-type: bogus") {
+		t.Errorf("Expected an invalid push type error, got %v", err)
+	}
+
+	if want := []string{"alert", "background"}; !slices.Equal(pushTypes, want) {
+		t.Errorf("Expected push types %v, got %v", want, pushTypes)
+	}
+}
+
+func TestCheckPayloadSize(t *testing.T) {
+	testCases := map[string]struct {
+		pushType notification.PushType
+		size     int
+		wantErr  string
+	}{
+		"Alert at limit":         {notification.Alert, MaxPayloadSize, ""},
+		"Alert over limit":       {notification.Alert, MaxPayloadSize + 1, "payload too large: 4097 bytes (max 4096)"},
+		"Background over limit":  {notification.Background, MaxPayloadSize + 1, "payload too large: 4097 bytes (max 4096)"},
+		"Liveactivity at limit":  {notification.Liveactivity, MaxPayloadSize, ""},
+		"Voip above alert limit": {notification.Voip, MaxPayloadSize + 1, ""},
+		"Voip at limit":          {notification.Voip, MaxVoIPPayloadSize, ""},
+		"Voip over limit":        {notification.Voip, MaxVoIPPayloadSize + 1, "payload too large for Voip: 5121 bytes (max 5120)"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := checkPayloadSize(tc.pushType, tc.size)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Expected error %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClient_Push_ServerError(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode int
+		reason     string
+		apnsID     string
+		wantErr    string // Expected error string (APNsReason, or part of a generic error message)
+	}{
+		"BadRequest_BadDeviceToken": {
+			statusCode: http.StatusBadRequest,
+			reason:     "BadDeviceToken",
+			apnsID:     "error-apns-id-1",
+			wantErr:    "BadDeviceToken",
+		},
+		"Forbidden_MissingProviderToken": {
+			statusCode: http.StatusForbidden,
+			reason:     "MissingProviderToken",
+			apnsID:     "error-apns-id-2",
+			wantErr:    "MissingProviderToken",
+		},
+		"Forbidden_ExpiredProviderToken": {
+			statusCode: http.StatusForbidden,
+			reason:     "ExpiredProviderToken",
+			apnsID:     "error-apns-id-3",
+			wantErr:    "ExpiredProviderToken",
+		},
+		"Forbidden_InvalidProviderToken": {
+			statusCode: http.StatusForbidden,
+			reason:     "InvalidProviderToken",
+			apnsID:     "error-apns-id-4",
+			wantErr:    "InvalidProviderToken",
+		},
+		"Forbidden_Forbidden": {
+			statusCode: http.StatusForbidden,
+			reason:     "Forbidden",
+			apnsID:     "error-apns-id-5",
+			wantErr:    "Forbidden",
+		},
+		"BadRequest_BadPath": {
+			statusCode: http.StatusBadRequest,
+			reason:     "BadPath",
+			apnsID:     "error-apns-id-6",
+			wantErr:    "BadPath",
+		},
+		"BadRequest_BadMessageId": {
+			statusCode: http.StatusBadRequest,
+			reason:     "BadMessageId",
+			apnsID:     "error-apns-id-7",
+			wantErr:    "BadMessageId",
+		},
+		"BadRequest_MissingTopic": {
+			statusCode: http.StatusBadRequest,
+			reason:     "MissingTopic",
+			apnsID:     "error-apns-id-8",
+			wantErr:    "MissingTopic",
+		},
+		"BadRequest_TopicDiscrepancy": {
+			statusCode: http.StatusBadRequest,
+			reason:     "TopicDiscrepancy",
+			apnsID:     "error-apns-id-9",
+			wantErr:    "TopicDiscrepancy",
+		},
+		"BadRequest_DeviceTokenNotForTopic": {
+			statusCode: http.StatusBadRequest,
+			reason:     "DeviceTokenNotForTopic",
+			apnsID:     "error-apns-id-10",
+			wantErr:    "DeviceTokenNotForTopic",
+		},
+		"Gone_Unregistered": {
+			statusCode: http.StatusGone,
+			reason:     "Unregistered",
+			apnsID:     "error-apns-id-11",
+			wantErr:    "Unregistered",
+		},
+		"PayloadTooLarge": {
+			statusCode: http.StatusRequestEntityTooLarge,
+			reason:     "PayloadTooLarge",
+			apnsID:     "error-apns-id-12",
+			wantErr:    "PayloadTooLarge",
+		},
+		"TooManyRequests_TooManyProviderTokenUpdates": {
+			statusCode: http.StatusTooManyRequests,
+			reason:     "TooManyProviderTokenUpdates",
+			apnsID:     "error-apns-id-13",
+			wantErr:    "TooManyProviderTokenUpdates",
+		},
+		"TooManyRequests_TooManyRequests": {
+			statusCode: http.StatusTooManyRequests,
+			reason:     "TooManyRequests",
+			apnsID:     "error-apns-id-14",
+			wantErr:    "TooManyRequests",
+		},
+		"InternalServerError": {
+			statusCode: http.StatusInternalServerError,
+			reason:     "InternalServerError",
+			apnsID:     "error-apns-id-15",
+			wantErr:    "InternalServerError",
+		},
+		"ServiceUnavailable": {
+			statusCode: http.StatusServiceUnavailable,
+			reason:     "ServiceUnavailable",
+			apnsID:     "error-apns-id-16",
+			wantErr:    "ServiceUnavailable",
+		},
+		"UnknownError_501": { // e.g., 501 Not Implemented, when no APNs reason is provided
+			statusCode: http.StatusNotImplemented,
+			reason:     "", // Reason is empty for non-APNs errors or unknown ones
+			apnsID:     "error-apns-id-17",
+			wantErr:    fmt.Sprintf("APNs request failed with status %d", http.StatusNotImplemented),
+		},
+		"Success_ButNotError": { // Case where 200 OK does not result in an Error
+			statusCode: http.StatusOK,
+			reason:     "",
+			apnsID:     "success-apns-id",
+			wantErr:    "", // Expect no error
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("apns-id", tc.apnsID)
+				w.WriteHeader(tc.statusCode)
+				if tc.reason != "" {
+					_, _ = w.Write([]byte(fmt.Sprintf(`{"reason": "%s"}`, tc.reason)))
+				} else if tc.statusCode != http.StatusOK {
+					_, _ = w.Write([]byte(`{"message": "Generic error"}`))
+				}
+			}))
+			defer server.Close()
+
+			mockInitializer := appleapi.DefaultHTTPClientInitializer()
+			tp := &MockTokenProvider{Token: "dummy-token"}
+
+			client, err := NewClient(mockInitializer, tp)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			client.inner.Host = server.URL
+
+			n := &Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "invalid-device-token",
+				Type:        notification.Alert,
+				Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+			}
+
+			_, err = client.Push(context.Background(), n)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, but got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, but got nil")
+			}
+
+			apnsErr, isError := err.(*Error)
+
+			if tc.reason != "" { // Expect Error if Reason is set
+				if !isError {
+					t.Fatalf("Expected error of type *Error for reason '%s', got %T", tc.reason, err)
+				}
+				if apnsErr.Reason != tc.wantErr {
+					t.Errorf("Expected reason '%s', got '%s'", tc.wantErr, apnsErr.Reason)
+				}
+				if apnsErr.StatusCode != tc.statusCode {
+					t.Errorf("Expected status code %d, got %d", tc.statusCode, apnsErr.StatusCode)
+				}
+			} else { // Expect generic error if Reason is not set
+				if isError {
+					t.Fatalf("Did not expect error of type *Error for status %d, but got one: %v", tc.statusCode, apnsErr)
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected error message to contain '%s', but got: %v", tc.wantErr, err)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Push_WithTimeout(t *testing.T) {
+	// Reverted to httptest.NewServer approach due to synctest limitations with net/http's internal I/O blocking.
+	// synctest is powerful for CPU-bound concurrency and deterministic time advancement with durably blocked goroutines,
+	// but it struggles with real (or simulated via net.Pipe) network I/O which is not considered "durably blocked".
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond) // Simulate a slow response
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Mock initializer for NewClient
+	mockInitializer := appleapi.DefaultHTTPClientInitializer()
+	tp := &MockTokenProvider{Token: "dummy-token"}
+
+	client, err := NewClient(mockInitializer, tp)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.inner.Host = server.URL                          // Manually set the host for testing
+	client.inner.HTTPClient.Timeout = 50 * time.Millisecond // Manually set the timeout
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	// Use context with a slightly longer timeout to ensure HTTP client's timeout is tested
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.Push(ctx, n)
+	duration := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected a timeout error, but got nil")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// ok
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		// ok
+	} else {
+		t.Fatalf("Expected timeout error, got: %v", err)
+	}
+
+	// Check for specific timeout errors
+	if !strings.Contains(err.Error(), "Client.Timeout exceeded") && !strings.Contains(err.Error(), "context deadline exceeded") && !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Expected a timeout error, but got: %v (type %T)", err, err)
+	}
+
+	// Assert that the timeout occurred approximately within the expected time frame
+	expectedTimeout := client.inner.HTTPClient.Timeout
+	// Allow some buffer for real-world timing variations
+	if duration < expectedTimeout || duration > expectedTimeout*3 {
+		t.Errorf("Expected timeout duration around %v, but got %v", expectedTimeout, duration)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond) // Simulate a slow response
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	const timeout = 50 * time.Millisecond
+	client, err := NewClientWithToken(&MockTokenProvider{Token: "dummy-token"}, WithTimeout(timeout), appleapi.WithDevelopment())
+	if err != nil {
+		t.Fatalf("NewClientWithToken failed: %v", err)
+	}
+	if got := client.inner.HTTPClient.Timeout; got != timeout {
+		t.Errorf("Expected HTTP client timeout %v, got %v", timeout, got)
+	}
+	if !client.inner.Development {
+		t.Error("Expected WithTimeout to compose with other options")
+	}
+	client.inner.Host = server.URL
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	start := time.Now()
+	_, err = client.Push(context.Background(), n)
+	if err == nil {
+		t.Fatal("Expected a timeout error, but got nil")
+	}
+	if !strings.Contains(err.Error(), "Client.Timeout exceeded") {
+		t.Errorf("Expected a client timeout error, got %v", err)
+	}
+	if d := time.Since(start); d >= 200*time.Millisecond {
+		t.Errorf("Expected the request to time out after about %v, took %v", timeout, d)
+	}
+}
+
+func TestClient_Push_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(500 * time.Millisecond): // Simulate a slow response
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	testCases := map[string]struct {
+		requestTimeout time.Duration
+		callerTimeout  time.Duration
+		wantMax        time.Duration
+	}{
+		"Per-request timeout": {
+			requestTimeout: 50 * time.Millisecond,
+			wantMax:        250 * time.Millisecond,
+		},
+		"Tighter caller deadline wins": {
+			requestTimeout: 5 * time.Second,
+			callerTimeout:  50 * time.Millisecond,
+			wantMax:        250 * time.Millisecond,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "dummy-token"})
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			client.inner.Host = server.URL
+			client.RequestTimeout = tc.requestTimeout
+
+			ctx := context.Background()
+			if tc.callerTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.callerTimeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			_, err = client.Push(ctx, n)
+			duration := time.Since(start)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+			}
+			if duration > tc.wantMax {
+				t.Errorf("Expected push to time out within %v, but took %v", tc.wantMax, duration)
+			}
+		})
+	}
+}
+
+func TestClient_Push_HTTP2Required(t *testing.T) {
+	// httptest TLS servers only negotiate HTTP/1.1 unless EnableHTTP2 is set.
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	client, err := NewClientWithToken(&MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClientWithToken failed: %v", err)
+	}
+	client.inner.HTTPClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+	client.inner.Host = server.URL
+
+	_, err = client.Push(context.Background(), n)
+	if !errors.Is(err, ErrHTTP2Required) {
+		t.Fatalf("Expected ErrHTTP2Required, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "HTTP/1.1") {
+		t.Errorf("Expected error to mention the negotiated protocol, got: %v", err)
+	}
+
+	client.Gateway = true
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Expected push through a gateway to succeed, got: %v", err)
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	testCases := []struct {
+		name     string
+		apnsErr  *Error
+		expected string
+	}{
+		{
+			name: "Without Timestamp",
+			apnsErr: &Error{
+				StatusCode: 400,
+				Reason:     "BadDeviceToken",
+				Timestamp:  0,
+			},
+			expected: "APNs error: status=400 reason=BadDeviceToken",
+		},
+		{
+			name: "With Timestamp",
+			apnsErr: &Error{
+				StatusCode: 500,
+				Reason:     "InternalServerError",
+				Timestamp:  1678886400000,
+			},
+			expected: "APNs error: status=500 reason=InternalServerError timestamp=1678886400000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if errStr := tc.apnsErr.Error(); errStr != tc.expected {
+				t.Errorf("Error.Error() got = %q, want %q", errStr, tc.expected)
+			}
+		})
+	}
+}
+
+func createCert(t *testing.T) *tls.Certificate {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "test.local",
+		},
 		NotBefore: time.Now().Add(-time.Hour),
 		NotAfter:  time.Now().Add(time.Hour),
 		KeyUsage:  x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
@@ -718,349 +2091,1079 @@ func createCert(t *testing.T) *tls.Certificate {
 			x509.ExtKeyUsageServerAuth,
 			x509.ExtKeyUsageClientAuth,
 		},
-		BasicConstraintsValid: true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  priv,
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	errGone := &Error{StatusCode: http.StatusGone, Reason: "Unregistered"}
+	errBad := &Error{StatusCode: http.StatusBadRequest, Reason: "BadDeviceToken"}
+	errTimeout := errors.New("timeout")
+
+	want := []FailureEntry{
+		{Token: "token-a", Err: errBad},
+		{Token: "token-b", Err: errTimeout},
+		{Token: "token-c", Err: errGone},
+		{Token: "token-d", Err: errBad},
+	}
+	const wantMessage = "APNs batch failed for tokens: [token-a token-b token-c token-d]"
+
+	for i := range 20 {
+		// Build the map in a different insertion order each time.
+		failures := make(map[string]error)
+		for j := range want {
+			e := want[(i+j)%len(want)]
+			failures[e.Token] = e.Err
+		}
+		multiErr := &MultiError{Failures: failures}
+
+		if got := multiErr.Len(); got != len(want) {
+			t.Fatalf("run %d: Len() = %d, want %d", i, got, len(want))
+		}
+		if got := multiErr.Error(); got != wantMessage {
+			t.Fatalf("run %d: Error() = %q, want %q", i, got, wantMessage)
+		}
+		entries := multiErr.Entries()
+		if len(entries) != len(want) {
+			t.Fatalf("run %d: Entries() returned %d entries, want %d", i, len(entries), len(want))
+		}
+		for k := range want {
+			if entries[k].Token != want[k].Token || entries[k].Err != want[k].Err {
+				t.Fatalf("run %d: Entries()[%d] = %+v, want %+v", i, k, entries[k], want[k])
+			}
+		}
+	}
+
+	empty := &MultiError{}
+	if empty.Len() != 0 || len(empty.Entries()) != 0 {
+		t.Errorf("Expected an empty MultiError to have no entries, got %d", empty.Len())
+	}
+}
+
+func TestMultiError_Unwrap(t *testing.T) {
+	single := &MultiError{Failures: map[string]error{
+		"token-a": &Error{StatusCode: http.StatusGone, Reason: "Unregistered"},
+	}}
+	var err error = single
+	if !errors.Is(err, ErrUnregistered) {
+		t.Error("Expected errors.Is(single, ErrUnregistered) to be true")
+	}
+	if errors.Is(err, ErrBadDeviceToken) {
+		t.Error("Expected errors.Is(single, ErrBadDeviceToken) to be false")
+	}
+	var apnsErr *Error
+	if !errors.As(err, &apnsErr) || apnsErr.Reason != "Unregistered" {
+		t.Errorf("Expected errors.As to find the *Error, got %v", apnsErr)
+	}
+
+	mixed := &MultiError{Failures: map[string]error{
+		"token-a": errors.New("timeout"),
+		"token-b": fmt.Errorf("wrapped: %w", &Error{StatusCode: http.StatusBadRequest, Reason: "BadDeviceToken"}),
+		"token-c": context.Canceled,
+	}}
+	err = fmt.Errorf("push failed: %w", mixed)
+	if !errors.Is(err, ErrBadDeviceToken) || !errors.Is(err, context.Canceled) {
+		t.Error("Expected errors.Is to match any of the failures")
+	}
+	if errors.Is(err, ErrUnregistered) {
+		t.Error("Expected errors.Is(mixed, ErrUnregistered) to be false")
+	}
+	if !errors.As(err, &apnsErr) || apnsErr.Reason != "BadDeviceToken" {
+		t.Errorf("Expected errors.As to find the *Error, got %v", apnsErr)
+	}
+	if got := mixed.Unwrap(); len(got) != 3 || got[2] != context.Canceled {
+		t.Errorf("Expected the failures in token order, got %v", got)
+	}
+}
+
+func TestClient_PushMulti(t *testing.T) {
+	bundleID := "com.example.app"
+	successApnsID := "success-apns-id"
+
+	// Mock APNs server
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, Path)
+		switch token {
+		case "token-success-1", "token-success-2", "token-success-3":
+			w.Header().Set("apns-id", successApnsID)
+			w.WriteHeader(http.StatusOK)
+		case "token-fail-baddevicetoken":
+			w.Header().Set("apns-id", "fail-apns-id")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		case "token-fail-server-error":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"reason":"InternalServerError"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"reason":"UnknownToken"}`))
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	baseNotification := &Notification{
+		BundleID: bundleID,
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	invalidNotification := &Notification{
+		Type:    notification.Alert,
+		Payload: &Payload{APS: payload.APS{Alert: "test"}},
+		// Missing BundleID
+	}
+
+	testCases := map[string]struct {
+		notification    *Notification
+		tokens          []string
+		tokenLimits     int
+		wantSuccesses   int
+		wantFailures    int
+		wantErrStr      string
+		checkMultiError func(t *testing.T, err error)
+	}{
+		"All Success": {
+			notification:  baseNotification,
+			tokens:        []string{"token-success-1", "token-success-2", "token-success-3"},
+			wantSuccesses: 3,
+			wantFailures:  0,
+		},
+		"Partial Failure": {
+			notification:  baseNotification,
+			tokens:        []string{"token-success-1", "token-fail-baddevicetoken", "token-success-2"},
+			wantSuccesses: 2,
+			wantFailures:  1,
+			wantErrStr:    "APNs batch failed",
+			checkMultiError: func(t *testing.T, err error) {
+				multiErr, ok := err.(*MultiError)
+				if !ok {
+					t.Fatalf("Expected *MultiError, got %T", err)
+				}
+				if _, exists := multiErr.Failures["token-fail-baddevicetoken"]; !exists {
+					t.Errorf("Expected failure for 'token-fail-baddevicetoken'")
+				}
+			},
+		},
+		"First Token Fails": {
+			notification:  baseNotification,
+			tokens:        []string{"token-fail-server-error", "token-success-1"},
+			wantSuccesses: 0, // A failed delivery is not reported as a success
+			wantFailures:  0, // Not a MultiError
+			wantErrStr:    "InternalServerError",
+		},
+		"Empty Token List": {
+			notification: baseNotification,
+			tokens:       []string{},
+			wantErrStr:   "token list is empty",
+		},
+		"Single Token": {
+			notification:  baseNotification,
+			tokens:        []string{"token-success-1"},
+			wantSuccesses: 1,
+			wantFailures:  0,
+		},
+		"Pre-validation Failure": {
+			notification: invalidNotification,
+			tokens:       []string{"token-success-1"},
+			wantErrStr:   "BundleID is required",
+		},
+		"Custom Token Limit Exceeded": {
+			notification: baseNotification,
+			tokens:       []string{"token1", "token2", "token3", "token4", "token5"},
+			tokenLimits:  3,
+			wantErrStr:   "token limit exceeded: got 5 tokens, maximum allowed is 3",
+		},
+	}
+
+	tp := &MockTokenProvider{Token: "test-token"}
+	client, err := NewClientWithToken(tp)
+	if err != nil {
+		t.Fatalf("NewClientWithToken failed: %v", err)
+	}
+	tr, ok := client.inner.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Client transport type check failed")
+	}
+	tr.TLSClientConfig.InsecureSkipVerify = true
+	client.inner.Host = server.URL
+
+	for name, tc := range testCases {
+		if tc.tokenLimits == 0 {
+			client.TokenLimits = MaxTokens
+		} else {
+			client.TokenLimits = tc.tokenLimits
+		}
+		t.Run(name, func(t *testing.T) {
+			responses, err := client.PushMulti(context.Background(), tc.notification, tc.tokens)
+
+			if tc.wantErrStr != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing '%s', but got nil", tc.wantErrStr)
+				}
+				if !strings.Contains(err.Error(), tc.wantErrStr) {
+					t.Errorf("Expected error '%s', got '%s'", tc.wantErrStr, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+
+			if len(responses) != tc.wantSuccesses {
+				t.Errorf("Expected %d successful responses, got %d", tc.wantSuccesses, len(responses))
+			}
+
+			if multiErr, ok := err.(*MultiError); ok {
+				if len(multiErr.Failures) != tc.wantFailures {
+					t.Errorf("Expected %d failures, got %d", tc.wantFailures, len(multiErr.Failures))
+				}
+				if tc.checkMultiError != nil {
+					tc.checkMultiError(t, err)
+				}
+			} else if tc.wantFailures > 0 {
+				t.Errorf("Expected MultiError with %d failures, but didn't get a MultiError", tc.wantFailures)
+			}
+		})
+	}
+}
+
+// mockCancelRoundTripper is a mock RoundTripper that cancels a context on a specified call number.
+type mockCancelRoundTripper struct {
+	// cancelAtCall specifies which call to RoundTrip should trigger the context cancellation.
+	// If set to 0, it will never cancel.
+	cancelAtToken string
+
+	// callCount tracks the number of times RoundTrip has been called.
+	callCount int
+
+	// cancel is the context cancellation function.
+	cancel context.CancelFunc
+
+	// t is the testing object for logging.
+	t *testing.T
+	cancelDone    chan struct{} // Added: Channel to signal cancellation
+	mu            sync.Mutex    // Added: For protecting internal state if needed
+}
+
+func (m *mockCancelRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	token := path.Base(r.URL.Path)
+	m.t.Logf("RoundTrip called. Request for token: %s, cancelAtToken: %s", token, m.cancelAtToken)
+
+	// Check if context is already cancelled or if another goroutine has signaled cancellation
+	select {
+	case <-r.Context().Done():
+		m.t.Logf("Context is already canceled before processing request for token: %s", token)
+		return nil, r.Context().Err()
+	case <-m.cancelDone: // If this channel is closed, cancellation has been signaled
+		m.t.Logf("Cancellation signaled via channel for token: %s", token)
+		return nil, context.Canceled // Return context.Canceled directly as it's the expected error
+	default:
+		// Not cancelled yet, proceed
+	}
+
+	if token == m.cancelAtToken {
+		m.t.Logf("Cancelling context for token: %s", token)
+		m.cancel()
+		// Only close cancelDone once to avoid panics. Use a mutex if multiple goroutines could try to close it.
+		m.mu.Lock()
+		select {
+		case <-m.cancelDone:
+			// Already closed
+		default:
+			close(m.cancelDone) // Signal cancellation to other goroutines
+		}
+		m.mu.Unlock()
+		return nil, context.Canceled
+	}
+
+	// Simulate some work, potentially checking for cancellation during this work
+	select {
+	case <-r.Context().Done():
+		m.t.Logf("Context cancelled during simulated work for token: %s", token)
+		return nil, r.Context().Err()
+	case <-time.After(1 * time.Millisecond): // Simulate work that takes a small amount of time
+		// Work completed, proceed to return success
+	case <-m.cancelDone:
+		m.t.Logf("Cancellation signaled via channel during simulated work for token: %s", token)
+		return nil, context.Canceled
+	}
+
+	// Return a dummy successful response for calls before cancellation.
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"apns-id": []string{"dummy-apns-id"}},
+		Body:       io.NopCloser(strings.NewReader(`{"reason":""}`)),
+	}, nil
+}
+
+func TestClient_PushMulti_ContextCancellation(t *testing.T) {
+	baseNotification := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	testCases := map[string]struct {
+		tokens        []string
+				cancelAtToken string
+		wantErr       bool
+		wantSuccesses int
+		wantFailures  int
+		checkErr      func(t *testing.T, err error)
+	}{
+		"1 Token, No Cancellation": {
+			tokens:        []string{"token1"},
+			cancelAtToken: "", // No cancellation
+			wantErr:       false,
+			wantSuccesses: 1,
+			wantFailures:  0,
+		},
+		"3 Tokens, Cancel on 1st Call (Sync part)": {
+			tokens:        []string{"token1", "token2", "token3"},
+			cancelAtToken: "token1",
+			wantErr:       true,
+			wantSuccesses: 0,
+			wantFailures:  0, // No MultiError, the first sync request fails.
+			checkErr: func(t *testing.T, err error) {
+				if _, ok := err.(*MultiError); ok {
+					t.Errorf("Expected a single error, not a MultiError")
+				}
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("Expected error to be context.Canceled, got %v", err)
+				}
+			},
+		},
+		"3 Tokens, Cancel on 2nd Call (Async part)": {
+			tokens:        []string{"token1", "token2", "token3"},
+			cancelAtToken: "token2",
+			wantErr:       true,
+			wantSuccesses: 1, // The first one should succeed.
+			wantFailures:  2, // The remaining two should fail.
+			checkErr: func(t *testing.T, err error) {
+				multiErr, ok := err.(*MultiError)
+				if !ok {
+					t.Fatalf("Expected error of type *MultiError, got %T", err)
+				}
+				if len(multiErr.Failures) != 2 {
+					t.Fatalf("Expected 2 failures, got %d", len(multiErr.Failures))
+				}
+				foundCanceled := false
+				for _, failureErr := range multiErr.Failures {
+					if errors.Is(failureErr, context.Canceled) {
+						foundCanceled = true
+					}
+				}
+				if !foundCanceled {
+					t.Errorf("Expected at least one failure to be context.Canceled, but none was. Failures: %v", multiErr.Failures)
+				}
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mockTransport := &mockCancelRoundTripper{
+				cancel:        cancel,
+				cancelAtToken: tc.cancelAtToken,
+				t:             t,
+				cancelDone:    make(chan struct{}),
+			}
+
+			client, err := NewClient(
+				appleapi.DefaultHTTPClientInitializer(),
+				&MockTokenProvider{Token: "test-token"},
+				appleapi.WithTransport(mockTransport),
+			)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			client.inner.Host = "https://localhost"
+
+			responses, err := client.PushMulti(ctx, baseNotification, tc.tokens)
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, but got nil")
+				}
+				if tc.checkErr != nil {
+					tc.checkErr(t, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Expected no error, but got: %v", err)
+				}
+			}
+
+			if len(responses) != tc.wantSuccesses {
+				t.Errorf("Expected %d successful responses, got %d", tc.wantSuccesses, len(responses))
+			}
+
+			if multiErr, ok := err.(*MultiError); ok {
+				if len(multiErr.Failures) != tc.wantFailures {
+					t.Errorf("Expected %d failures, got %d", tc.wantFailures, len(multiErr.Failures))
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Transformers(t *testing.T) {
+	var mu sync.Mutex
+	var gotCollapseIDs []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		gotCollapseIDs = append(gotCollapseIDs, r.Header.Get("apns-collapse-id"))
+		mu.Unlock()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	var order []string
+	client.Transformers = []func(*Notification) error{
+		func(n *Notification) error {
+			order = append(order, "first")
+			n.CollapseID = "first"
+			return nil
+		},
+		func(n *Notification) error {
+			order = append(order, "second")
+			n.CollapseID += "-second"
+			return nil
+		},
 	}
 
-	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, order); diff != "" {
+		t.Errorf("transformer order mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"first-second"}, gotCollapseIDs); diff != "" {
+		t.Errorf("apns-collapse-id mismatch (-want +got):\n%s", diff)
+	}
+	if n.CollapseID != "" {
+		t.Errorf("caller's notification was modified: CollapseID = %q", n.CollapseID)
+	}
+
+	gotCollapseIDs = nil
+	if _, err := client.PushMulti(context.Background(), n, []string{"token1", "token2"}); err != nil {
+		t.Fatalf("PushMulti failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"first-second", "first-second"}, gotCollapseIDs); diff != "" {
+		t.Errorf("apns-collapse-id mismatch (-want +got):\n%s", diff)
+	}
+	if n.CollapseID != "" {
+		t.Errorf("caller's notification was modified: CollapseID = %q", n.CollapseID)
+	}
+
+	gotCollapseIDs = nil
+	client.Transformers = append(client.Transformers, func(n *Notification) error {
+		return errors.New("policy violation")
+	})
+	_, err := client.Push(context.Background(), n)
+	if err == nil || !strings.Contains(err.Error(), "policy violation") {
+		t.Fatalf("expected transformer error, got: %v", err)
+	}
+	if len(gotCollapseIDs) != 0 {
+		t.Errorf("expected no request to be sent, but %d were sent", len(gotCollapseIDs))
+	}
+}
+
+func TestClient_ContentType(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
 	if err != nil {
-		t.Fatalf("failed to create certificate: %v", err)
+		t.Fatalf("NewClient failed: %v", err)
 	}
+	client.inner.Host = server.URL
 
-	return &tls.Certificate{
-		Certificate: [][]byte{der},
-		PrivateKey:  priv,
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	client.ContentType = "application/json; charset=utf-8"
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	want := []string{"", "application/json; charset=utf-8"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Content-Type = %q, want %q", got, want)
 	}
 }
 
-func TestClient_PushMulti(t *testing.T) {
-	bundleID := "com.example.app"
-	successApnsID := "success-apns-id"
+func TestClient_Push_InvalidPriority(t *testing.T) {
+	var sent int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent++
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
 
-	// Mock APNs server
-	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := strings.TrimPrefix(r.URL.Path, Path)
-		switch token {
-		case "token-success-1", "token-success-2", "token-success-3":
-			w.Header().Set("apns-id", successApnsID)
-			w.WriteHeader(http.StatusOK)
-		case "token-fail-baddevicetoken":
-			w.Header().Set("apns-id", "fail-apns-id")
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(`{"reason":"BadDeviceToken"}`))
-		case "token-fail-server-error":
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(`{"reason":"InternalServerError"}`))
-		default:
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(`{"reason":"UnknownToken"}`))
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Priority:    99,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), "invalid apns-priority") {
+		t.Errorf("Push() error = %v, want an invalid apns-priority error", err)
+	}
+	if sent != 0 {
+		t.Errorf("Expected no request to be sent, got %d", sent)
+	}
+
+	// A priority made invalid after validation must not be dropped silently either.
+	if _, err := client.newRequest(context.Background(), n, nil); err == nil || !strings.Contains(err.Error(), "invalid apns-priority") {
+		t.Errorf("newRequest() error = %v, want an invalid apns-priority error", err)
+	}
+}
+
+func TestClient_Push_ChannelID(t *testing.T) {
+	var gotReq *http.Request
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotReq = r
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:  "com.example.app",
+		ChannelID: "dHN0LXNyY2gtY2hubA==",
+		Type:      notification.Liveactivity,
+		Payload:   &Payload{APS: payload.APS{Event: "update", ContentState: map[string]any{"score": 1}}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if got := gotReq.Header.Get("apns-channel-id"); got != n.ChannelID {
+		t.Errorf("Expected apns-channel-id %q, got %q", n.ChannelID, got)
+	}
+	if gotReq.URL.Path != BroadcastPath+"com.example.app" {
+		t.Errorf("Expected path %s, got %s", BroadcastPath+"com.example.app", gotReq.URL.Path)
+	}
+
+	// Notifications sent to a device carry no channel header.
+	n.ChannelID = ""
+	n.DeviceToken = "test-device-token"
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if _, ok := gotReq.Header["Apns-Channel-Id"]; ok {
+		t.Error("Expected no apns-channel-id header")
+	}
+	if gotReq.URL.Path != Path+"test-device-token" {
+		t.Errorf("Expected path %s, got %s", Path+"test-device-token", gotReq.URL.Path)
+	}
+}
+
+func TestClient_PushRaw(t *testing.T) {
+	var gotReq *http.Request
+	var gotBody []byte
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotReq = r
+		gotBody, _ = io.ReadAll(r.Body)
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Priority:    priority.Conserve,
+		CollapseID:  "score",
+		Headers:     map[string]string{"X-Trace-Id": "trace-1"},
+	}
+	body := []byte(`{"aps":{"alert":"rendered elsewhere"},"template":"v2"}`)
+	if _, err := client.PushRaw(context.Background(), n, body); err != nil {
+		t.Fatalf("PushRaw failed: %v", err)
+	}
+	if string(gotBody) != string(body) {
+		t.Errorf("Expected body %s, got %s", body, gotBody)
+	}
+	wantHeaders := map[string]string{
+		"apns-push-type":   "alert",
+		"apns-topic":       "com.example.app",
+		"apns-priority":    "5",
+		"apns-collapse-id": "score",
+		"X-Trace-Id":       "trace-1",
+		"Authorization":    "Bearer test-token",
+	}
+	for name, want := range wantHeaders {
+		if got := gotReq.Header.Get(name); got != want {
+			t.Errorf("Expected header %s=%q, got %q", name, want, got)
 		}
-	}))
-	server.EnableHTTP2 = true
-	server.StartTLS()
-	defer server.Close()
+	}
 
-	baseNotification := &Notification{
-		BundleID: bundleID,
-		Type:     notification.Alert,
-		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	gotReq = nil
+	oversize := []byte(`{"aps":{"alert":"` + strings.Repeat("a", MaxPayloadSize) + `"}}`)
+	if _, err := client.PushRaw(context.Background(), n, oversize); err == nil || !strings.Contains(err.Error(), "payload too large") {
+		t.Errorf("PushRaw() error = %v, want a payload too large error", err)
 	}
-	invalidNotification := &Notification{
-		Type:    notification.Alert,
-		Payload: &Payload{APS: payload.APS{Alert: "test"}},
-		// Missing BundleID
+	n.CollapseID = strings.Repeat("c", MaxCollapseIDSize+1)
+	if _, err := client.PushRaw(context.Background(), n, body); err == nil {
+		t.Error("PushRaw() with an invalid notification: expected an error")
 	}
+	if gotReq != nil {
+		t.Error("Expected no request to be sent for rejected notifications")
+	}
+}
 
-	testCases := map[string]struct {
-		notification    *Notification
-		tokens          []string
-		tokenLimits     int
-		wantSuccesses   int
-		wantFailures    int
-		wantErrStr      string
-		checkMultiError func(t *testing.T, err error)
+func TestClient_PushRaw_Validation(t *testing.T) {
+	body := []byte(`{"aps":{"alert":"rendered elsewhere"}}`)
+	tests := map[string]struct {
+		configure   func(*Client)
+		bundleID    string
+		errContains string
 	}{
-		"All Success": {
-			notification:  baseNotification,
-			tokens:        []string{"token-success-1", "token-success-2", "token-success-3"},
-			wantSuccesses: 3,
-			wantFailures:  0,
-		},
-		"Partial Failure": {
-			notification:  baseNotification,
-			tokens:        []string{"token-success-1", "token-fail-baddevicetoken", "token-success-2"},
-			wantSuccesses: 2,
-			wantFailures:  1,
-			wantErrStr:    "APNs batch failed",
-			checkMultiError: func(t *testing.T, err error) {
-				multiErr, ok := err.(*MultiError)
-				if !ok {
-					t.Fatalf("Expected *MultiError, got %T", err)
-				}
-				if _, exists := multiErr.Failures["token-fail-baddevicetoken"]; !exists {
-					t.Errorf("Expected failure for 'token-fail-baddevicetoken'")
-				}
-			},
-		},
-		"First Token Fails": {
-			notification:  baseNotification,
-			tokens:        []string{"token-fail-server-error", "token-success-1"},
-			wantSuccesses: 1, // Expect one response object even on failure
-			wantFailures:  0, // Not a MultiError
-			wantErrStr:    "InternalServerError",
-		},
-		"Empty Token List": {
-			notification: baseNotification,
-			tokens:       []string{},
-			wantErrStr:   "token list is empty",
+		"default": {
+			bundleID: "com.Example.app",
 		},
-		"Single Token": {
-			notification:  baseNotification,
-			tokens:        []string{"token-success-1"},
-			wantSuccesses: 1,
-			wantFailures:  0,
+		"strict": {
+			configure:   func(c *Client) { c.StrictValidation = true },
+			bundleID:    "com.Example.app",
+			errContains: "uppercase",
 		},
-		"Pre-validation Failure": {
-			notification: invalidNotification,
-			tokens:       []string{"token-success-1"},
-			wantErrStr:   "BundleID is required",
+		"strict doubled suffix": {
+			configure:   func(c *Client) { c.StrictValidation = true },
+			bundleID:    "com.example.app.voip",
+			errContains: "topic suffix",
 		},
-		"Custom Token Limit Exceeded": {
-			notification: baseNotification,
-			tokens:       []string{"token1", "token2", "token3", "token4", "token5"},
-			tokenLimits:  3,
-			wantErrStr:   "token limit exceeded: got 5 tokens, maximum allowed is 3",
+		"skipped": {
+			configure: func(c *Client) { c.SkipValidation = true },
 		},
 	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			sent := false
+			client := newTestClient(t, roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				sent = true
+				return newResponse(http.StatusOK, ""), nil
+			}))
+			if tt.configure != nil {
+				tt.configure(client)
+			}
+			n := &Notification{
+				BundleID:    tt.bundleID,
+				DeviceToken: "test-device-token",
+				Type:        notification.Voip,
+			}
+			_, err := client.PushRaw(context.Background(), n, body)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("PushRaw() error = %v, want %q", err, tt.errContains)
+				}
+				if sent {
+					t.Error("Expected no request to be sent for a rejected notification")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PushRaw failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestNotification_WithToken(t *testing.T) {
+	var paths, bodies []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(b))
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	marshaler := &recordingMarshaler{}
+	client.Marshaler = marshaler
+
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	template := n.Template()
+	tokens := []string{"token-1", "token-2", "token-3"}
+	for _, token := range tokens {
+		c := template.WithToken(token)
+		if c.DeviceToken != token || c.Payload != template.Payload {
+			t.Fatalf("WithToken(%q) = %+v, want the template with the token", token, c)
+		}
+		if _, err := client.Push(context.Background(), c); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+	}
+	if template.DeviceToken != "" {
+		t.Errorf("template was modified: DeviceToken = %q", template.DeviceToken)
+	}
+	if marshaler.calls != 1 {
+		t.Errorf("Marshal called %d times for a template, want 1", marshaler.calls)
+	}
+	for i, token := range tokens {
+		if paths[i] != Path+token {
+			t.Errorf("request %d sent to %s, want %s", i, paths[i], Path+token)
+		}
+	}
+
+	// Without a template, copies are marshaled for every push, so a payload
+	// modified in between is sent as is. So is a new template of it.
+	marshaler.calls = 0
+	n.Payload.APS.Alert = "changed"
+	for _, c := range []*Notification{n.WithToken("token-1"), n.WithToken("token-2"), n.Template().WithToken("token-3")} {
+		if _, err := client.Push(context.Background(), c); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+		if want := `{"aps":{"alert":"changed"}}`; bodies[len(bodies)-1] != want {
+			t.Errorf("body = %s, want %s", bodies[len(bodies)-1], want)
+		}
+	}
+	if marshaler.calls != 3 {
+		t.Errorf("Marshal called %d times, want 3", marshaler.calls)
+	}
+
+	// A copy given a payload of its own is not sent with the template's body.
+	other := template.WithToken("token-1")
+	other.Payload = &Payload{APS: payload.APS{Alert: "other"}}
+	if _, err := client.Push(context.Background(), other); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if want := `{"aps":{"alert":"other"}}`; bodies[len(bodies)-1] != want {
+		t.Errorf("body = %s, want %s", bodies[len(bodies)-1], want)
+	}
+	if c := template.Clone(); c.body != nil {
+		t.Error("Clone of a template shares its marshaled payload")
+	}
+}
+
+func TestClient_PrepareBody(t *testing.T) {
+	var bodies []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	marshaler := &recordingMarshaler{}
+	client.Marshaler = marshaler
+
+	template := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	body, err := client.PrepareBody(template)
+	if err != nil {
+		t.Fatalf("PrepareBody failed: %v", err)
+	}
+	if want := `{"aps":{"alert":"test"}}`; string(body) != want {
+		t.Errorf("PrepareBody() = %s, want %s", body, want)
+	}
+
+	for _, token := range []string{"token-1", "token-2", "token-3"} {
+		n := *template
+		n.DeviceToken = token
+		res, err := client.PushWithBody(context.Background(), &n, body)
+		if err != nil {
+			t.Fatalf("PushWithBody failed: %v", err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("Expected StatusCode 200, got %d", res.StatusCode)
+		}
+	}
+	if marshaler.calls != 1 {
+		t.Errorf("Marshal called %d times, want 1", marshaler.calls)
+	}
+	for i, got := range bodies {
+		if got != string(body) {
+			t.Errorf("request %d body = %s, want %s", i, got, body)
+		}
+	}
+
+	if _, err := client.PrepareBody(&Notification{BundleID: "com.example.app", Type: notification.Alert}); err == nil {
+		t.Error("PrepareBody without a payload: expected an error")
+	}
+	if _, err := client.PushWithBody(context.Background(), template, body); err == nil {
+		t.Error("PushWithBody without a device token: expected an error")
+	}
+}
+
+func TestClient_AutoPriority(t *testing.T) {
+	var gotPriority string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotPriority = r.Header.Get("apns-priority")
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	alert := payload.APS{Alert: "test"}
+	testCases := map[string]struct {
+		autoPriority bool
+		pushType     notification.PushType
+		priority     priority.Priority
+		aps          payload.APS
+		want         string
+	}{
+		"alert":             {autoPriority: true, pushType: notification.Alert, aps: alert, want: "10"},
+		"voip":              {autoPriority: true, pushType: notification.Voip, aps: alert, want: "10"},
+		"background":        {autoPriority: true, pushType: notification.Background, aps: payload.APS{ContentAvailable: 1}, want: "5"},
+		"liveactivity":      {autoPriority: true, pushType: notification.Liveactivity, aps: payload.APS{Event: "update", ContentState: map[string]any{"score": 1}}, want: ""},
+		"explicit priority": {autoPriority: true, pushType: notification.Alert, priority: priority.Conserve, aps: alert, want: "5"},
+		"disabled":          {autoPriority: false, pushType: notification.Alert, aps: alert, want: ""},
+	}
 
-	tp := &MockTokenProvider{Token: "test-token"}
-	client, err := NewClientWithToken(tp)
-	if err != nil {
-		t.Fatalf("NewClientWithToken failed: %v", err)
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client.AutoPriority = tc.autoPriority
+			n := &Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "test-device-token",
+				Type:        tc.pushType,
+				Priority:    tc.priority,
+				Payload:     &Payload{APS: tc.aps},
+			}
+			if _, err := client.Push(context.Background(), n); err != nil {
+				t.Fatalf("Push failed: %v", err)
+			}
+			if gotPriority != tc.want {
+				t.Errorf("apns-priority = %q, want %q", gotPriority, tc.want)
+			}
+			if n.Priority != tc.priority {
+				t.Errorf("caller's notification was modified: Priority = %d", n.Priority)
+			}
+		})
 	}
-	tr, ok := client.inner.HTTPClient.Transport.(*http.Transport)
-	if !ok {
-		t.Fatalf("Client transport type check failed")
+}
+
+func TestClient_Push_EmptyBody(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode int
+		wantErr    string
+	}{
+		"OK": {
+			statusCode: http.StatusOK,
+		},
+		"InternalServerError": {
+			statusCode: http.StatusInternalServerError,
+			wantErr:    "APNs transport error: empty response body, status=500",
+		},
 	}
-	tr.TLSClientConfig.InsecureSkipVerify = true
-	client.inner.Host = server.URL
 
 	for name, tc := range testCases {
-		if tc.tokenLimits == 0 {
-			client.TokenLimits = MaxTokens
-		} else {
-			client.TokenLimits = tc.tokenLimits
-		}
 		t.Run(name, func(t *testing.T) {
-			responses, err := client.PushMulti(context.Background(), tc.notification, tc.tokens)
+			transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: tc.statusCode,
+					Header:     http.Header{"Apns-Id": []string{"empty-body-id"}},
+					Body:       io.NopCloser(strings.NewReader("")),
+					Request:    r,
+				}, nil
+			})
+			client := newTestClient(t, transport)
 
-			if tc.wantErrStr != "" {
-				if err == nil {
-					t.Fatalf("Expected error containing '%s', but got nil", tc.wantErrStr)
+			n := &Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "test-device-token",
+				Type:        notification.Alert,
+				Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+			}
+			res, err := client.Push(context.Background(), n)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Push failed: %v", err)
 				}
-				if !strings.Contains(err.Error(), tc.wantErrStr) {
-					t.Errorf("Expected error '%s', got '%s'", tc.wantErrStr, err.Error())
+				if res.APNsID != "empty-body-id" || res.StatusCode != http.StatusOK || res.Body != nil {
+					t.Errorf("Unexpected response: %+v", res)
 				}
-			} else if err != nil {
-				t.Fatalf("Expected no error, but got: %v", err)
+				return
 			}
-
-			if len(responses) != tc.wantSuccesses {
-				t.Errorf("Expected %d successful responses, got %d", tc.wantSuccesses, len(responses))
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("Expected error %q, got %v", tc.wantErr, err)
 			}
-
-			if multiErr, ok := err.(*MultiError); ok {
-				if len(multiErr.Failures) != tc.wantFailures {
-					t.Errorf("Expected %d failures, got %d", tc.wantFailures, len(multiErr.Failures))
-				}
-				if tc.checkMultiError != nil {
-					tc.checkMultiError(t, err)
-				}
-			} else if tc.wantFailures > 0 {
-				t.Errorf("Expected MultiError with %d failures, but didn't get a MultiError", tc.wantFailures)
+			var apnsErr *Error
+			if errors.As(err, &apnsErr) {
+				t.Errorf("Expected a generic error for an empty body, got %#v", apnsErr)
+			}
+			if res == nil || res.APNsID != "empty-body-id" || res.StatusCode != tc.statusCode {
+				t.Errorf("Expected the response metadata alongside the error, got %+v", res)
 			}
 		})
 	}
 }
 
-// mockCancelRoundTripper is a mock RoundTripper that cancels a context on a specified call number.
-type mockCancelRoundTripper struct {
-	// cancelAtCall specifies which call to RoundTrip should trigger the context cancellation.
-	// If set to 0, it will never cancel.
-	cancelAtToken string
-
-	// callCount tracks the number of times RoundTrip has been called.
-	callCount int
+func TestClient_SetTransport(t *testing.T) {
+	var baseHits int
+	base := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		baseHits++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Apns-Id": []string{"wrapped-id"}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+	client := newTestClient(t, base)
+
+	if err := client.SetTransport(nil); err == nil {
+		t.Error("Expected an error for a nil transport")
+	}
 
-	// cancel is the context cancellation function.
-	cancel context.CancelFunc
+	var authorization string
+	inner := client.HTTPClient().Transport
+	wrapper := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		authorization = r.Header.Get("Authorization")
+		return inner.RoundTrip(r)
+	})
+	if err := client.SetTransport(wrapper); err != nil {
+		t.Fatalf("SetTransport failed: %v", err)
+	}
 
-	// t is the testing object for logging.
-	t *testing.T
-	cancelDone    chan struct{} // Added: Channel to signal cancellation
-	mu            sync.Mutex    // Added: For protecting internal state if needed
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	res, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if res.APNsID != "wrapped-id" {
+		t.Errorf("Expected the response of the wrapped transport, got %+v", res)
+	}
+	if authorization != "Bearer test-token" {
+		t.Errorf("Expected the wrapper to see the bearer token, got %q", authorization)
+	}
+	if baseHits != 1 {
+		t.Errorf("Expected the wrapped transport to be called once, got %d", baseHits)
+	}
 }
 
-func (m *mockCancelRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
-	token := path.Base(r.URL.Path)
-	m.t.Logf("RoundTrip called. Request for token: %s, cancelAtToken: %s", token, m.cancelAtToken)
-
-	// Check if context is already cancelled or if another goroutine has signaled cancellation
-	select {
-	case <-r.Context().Done():
-		m.t.Logf("Context is already canceled before processing request for token: %s", token)
-		return nil, r.Context().Err()
-	case <-m.cancelDone: // If this channel is closed, cancellation has been signaled
-		m.t.Logf("Cancellation signaled via channel for token: %s", token)
-		return nil, context.Canceled // Return context.Canceled directly as it's the expected error
-	default:
-		// Not cancelled yet, proceed
+func TestClient_Push_AuthError(t *testing.T) {
+	var hits int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits++
+		return newResponse(http.StatusOK, ""), nil
+	})
+	errKey := errors.New("key revoked")
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Err: errKey}, appleapi.WithTransport(transport))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
 	}
+	client.MaxRetries = 2
 
-	if token == m.cancelAtToken {
-		m.t.Logf("Cancelling context for token: %s", token)
-		m.cancel()
-		// Only close cancelDone once to avoid panics. Use a mutex if multiple goroutines could try to close it.
-		m.mu.Lock()
-		select {
-		case <-m.cancelDone:
-			// Already closed
-		default:
-			close(m.cancelDone) // Signal cancellation to other goroutines
-		}
-		m.mu.Unlock()
-		return nil, context.Canceled
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
 	}
-
-	// Simulate some work, potentially checking for cancellation during this work
-	select {
-	case <-r.Context().Done():
-		m.t.Logf("Context cancelled during simulated work for token: %s", token)
-		return nil, r.Context().Err()
-	case <-time.After(1 * time.Millisecond): // Simulate work that takes a small amount of time
-		// Work completed, proceed to return success
-	case <-m.cancelDone:
-		m.t.Logf("Cancellation signaled via channel during simulated work for token: %s", token)
-		return nil, context.Canceled
+	_, err = client.Push(context.Background(), n)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Expected an *AuthError, got %v", err)
+	}
+	if !errors.Is(err, errKey) {
+		t.Errorf("Expected the error to wrap the token provider error, got %v", err)
+	}
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		t.Errorf("Expected the error not to be a *TransportError, got %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("Expected no request to be sent, got %d", hits)
+	}
+	if got := Classify(err); got != Permanent {
+		t.Errorf("Classify() = %v, want %v", got, Permanent)
 	}
-
-	// Return a dummy successful response for calls before cancellation.
-	return &http.Response{
-		StatusCode: http.StatusOK,
-		Header:     http.Header{"apns-id": []string{"dummy-apns-id"}},
-		Body:       io.NopCloser(strings.NewReader(`{"reason":""}`)),
-	}, nil
 }
 
-func TestClient_PushMulti_ContextCancellation(t *testing.T) {
-	baseNotification := &Notification{
-		BundleID: "com.example.app",
-		Type:     notification.Alert,
-		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+func TestClient_NewRequest_Expiration(t *testing.T) {
+	client, err := NewClientWithToken(&MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClientWithToken failed: %v", err)
 	}
 
-	testCases := map[string]struct {
-		tokens        []string
-				cancelAtToken string
-		wantErr       bool
-		wantSuccesses int
-		wantFailures  int
-		checkErr      func(t *testing.T, err error)
+	tests := map[string]struct {
+		setup      func(n *Notification)
+		wantHeader bool
+		want       string
 	}{
-		"1 Token, No Cancellation": {
-			tokens:        []string{"token1"},
-			cancelAtToken: "", // No cancellation
-			wantErr:       false,
-			wantSuccesses: 1,
-			wantFailures:  0,
+		"omitted": {
+			setup: func(n *Notification) {},
 		},
-		"3 Tokens, Cancel on 1st Call (Sync part)": {
-			tokens:        []string{"token1", "token2", "token3"},
-			cancelAtToken: "token1",
-			wantErr:       true,
-			wantSuccesses: 0,
-			wantFailures:  0, // No MultiError, the first sync request fails.
-			checkErr: func(t *testing.T, err error) {
-				if _, ok := err.(*MultiError); ok {
-					t.Errorf("Expected a single error, not a MultiError")
-				}
-				if !errors.Is(err, context.Canceled) {
-					t.Errorf("Expected error to be context.Canceled, got %v", err)
-				}
-			},
+		"ExpirationOnce": {
+			setup:      func(n *Notification) { n.Expiration = notification.ExpirationOnce },
+			wantHeader: true,
+			want:       "0",
 		},
-		"3 Tokens, Cancel on 2nd Call (Async part)": {
-			tokens:        []string{"token1", "token2", "token3"},
-			cancelAtToken: "token2",
-			wantErr:       true,
-			wantSuccesses: 1, // The first one should succeed.
-			wantFailures:  2, // The remaining two should fail.
-			checkErr: func(t *testing.T, err error) {
-				multiErr, ok := err.(*MultiError)
-				if !ok {
-					t.Fatalf("Expected error of type *MultiError, got %T", err)
-				}
-				if len(multiErr.Failures) != 2 {
-					t.Fatalf("Expected 2 failures, got %d", len(multiErr.Failures))
-				}
-				foundCanceled := false
-				for _, failureErr := range multiErr.Failures {
-					if errors.Is(failureErr, context.Canceled) {
-						foundCanceled = true
-					}
-				}
-				if !foundCanceled {
-					t.Errorf("Expected at least one failure to be context.Canceled, but none was. Failures: %v", multiErr.Failures)
-				}
-			},
+		"SetExpireImmediately": {
+			setup:      func(n *Notification) { n.SetExpireImmediately() },
+			wantHeader: true,
+			want:       "0",
+		},
+		"timestamp": {
+			setup:      func(n *Notification) { n.Expiration = notification.EpochTimeFromUnix(1700000000) },
+			wantHeader: true,
+			want:       "1700000000",
 		},
 	}
 
-	for name, tc := range testCases {
+	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-
-			mockTransport := &mockCancelRoundTripper{
-				cancel:        cancel,
-				cancelAtToken: tc.cancelAtToken,
-				t:             t,
-				cancelDone:    make(chan struct{}),
+			n := &Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "test-device-token",
+				Type:        notification.Alert,
+				Payload:     &Payload{APS: payload.APS{Alert: "test"}},
 			}
-
-			client, err := NewClient(
-				appleapi.DefaultHTTPClientInitializer(),
-				&MockTokenProvider{Token: "test-token"},
-				appleapi.WithTransport(mockTransport),
-			)
+			tt.setup(n)
+			req, err := client.newRequest(context.Background(), n, []byte(`{}`))
 			if err != nil {
-				t.Fatalf("NewClient failed: %v", err)
-			}
-			client.inner.Host = "https://localhost"
-
-			responses, err := client.PushMulti(ctx, baseNotification, tc.tokens)
-
-			if tc.wantErr {
-				if err == nil {
-					t.Fatalf("Expected an error, but got nil")
-				}
-				if tc.checkErr != nil {
-					tc.checkErr(t, err)
-				}
-			} else {
-				if err != nil {
-					t.Fatalf("Expected no error, but got: %v", err)
-				}
+				t.Fatalf("newRequest failed: %v", err)
 			}
-
-			if len(responses) != tc.wantSuccesses {
-				t.Errorf("Expected %d successful responses, got %d", tc.wantSuccesses, len(responses))
+			values, ok := req.Header["Apns-Expiration"]
+			if ok != tt.wantHeader {
+				t.Fatalf("apns-expiration present = %t, want %t", ok, tt.wantHeader)
 			}
-
-			if multiErr, ok := err.(*MultiError); ok {
-				if len(multiErr.Failures) != tc.wantFailures {
-					t.Errorf("Expected %d failures, got %d", tc.wantFailures, len(multiErr.Failures))
-				}
+			if ok && (len(values) != 1 || values[0] != tt.want) {
+				t.Errorf("apns-expiration = %v, want %q", values, tt.want)
 			}
 		})
 	}
+
+	n := &Notification{}
+	n.SetExpireImmediately()
+	if n.Expiration == notification.ExpirationOnce {
+		t.Error("Expected SetExpireImmediately not to share ExpirationOnce")
+	}
 }
@@ -0,0 +1,105 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+// TestClient_ConcurrentUse shares a client across goroutines that use every
+// push method at once. It is meant to be run with -race.
+func TestClient_ConcurrentUse(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			return nil, err
+		}
+		status, body := http.StatusOK, ""
+		if strings.HasSuffix(r.URL.Path, "-bad") {
+			status, body = http.StatusGone, `{"reason":"Unregistered"}`
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Apns-Id": []string{r.Header.Get("apns-id")}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+	client := newTestClient(t, transport)
+	err := client.Configure(
+		WithTokenLimits(10),
+		WithFastJSON(true),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithMaxInFlightBytes(1<<20),
+	)
+	if err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	client.AutoGenerateAPNsID = true
+	client.AutoPriority = true
+	client.DedupWindow = time.Minute
+	client.DedupeTokens = true
+	client.OnResult = func(string, int, string, time.Duration) {}
+
+	template := (&Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "shared"}},
+	}).Template()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			client.SetTokenProvider(&MockTokenProvider{Token: "rotated-token"})
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Options can only be applied until the pushes start.
+		for range 20 {
+			if err := client.Configure(WithTokenLimits(10)); err != nil && !errors.Is(err, ErrClientInUse) {
+				t.Errorf("Configure failed: %v", err)
+			}
+		}
+	}()
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			for i := range 20 {
+				token := fmt.Sprintf("token-%d-%d", g, i)
+				if _, err := client.Push(ctx, template.WithToken(token)); err != nil {
+					t.Errorf("Push failed: %v", err)
+				}
+				tokens := []string{token + "-a", token + "-b", token + "-bad", token + "-a"}
+				if _, err := client.PushMulti(ctx, template, tokens); err == nil {
+					t.Error("Expected PushMulti to report the bad token")
+				}
+				items := []BatchItem{
+					{Token: token + "-c", Notification: template},
+					{Token: token + "-d", Notification: template.Clone()},
+				}
+				if _, err := client.PushBatch(ctx, items); err != nil {
+					t.Errorf("PushBatch failed: %v", err)
+				}
+				_ = client.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := client.Stats().Sent, uint64(8*20*(1+3+2)); got != want {
+		t.Errorf("Stats().Sent = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/takimoto3/appleapi-core/token"
+)
+
+// validateCertificate checks that a client certificate can be used for authentication.
+func validateCertificate(cert *tls.Certificate) error {
+	if cert == nil {
+		return errors.New("certificate cannot be nil")
+	}
+	if len(cert.Certificate) == 0 || cert.PrivateKey == nil {
+		return errors.New("invalid certificate: empty certificate or private key")
+	}
+	return nil
+}
+
+// installCertificateHook makes the TLS configuration of the client's transport
+// ask the client for its certificate on every handshake, so that the certificate
+// can be replaced with SetCertificate, and labels the connections the transport
+// dials with the credential generation they were established with. It does
+// nothing if the transport is not an *http.Transport with a TLS configuration.
+func (cli *Client) installCertificateHook() {
+	tr, ok := cli.inner.HTTPClient.Transport.(*http.Transport)
+	if !ok || tr.TLSClientConfig == nil || tr.TLSClientConfig.GetClientCertificate != nil {
+		return
+	}
+	config := tr.TLSClientConfig.Clone()
+	if len(config.Certificates) > 0 {
+		cert := config.Certificates[0]
+		cli.cert.Store(&cert)
+	}
+	config.GetClientCertificate = cli.clientCertificate
+	tr.TLSClientConfig = config
+
+	dial := tr.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		gen := cli.authGen.Load()
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &authConn{Conn: conn, gen: gen}, nil
+	}
+	cli.certHook = true
+}
+
+// authConn is a connection labeled with the credential generation it was
+// dialed under.
+type authConn struct {
+	net.Conn
+	gen uint64
+}
+
+// staleConn reports whether conn, as passed to httptrace.ClientTrace.GotConn,
+// was established with credentials that have since been replaced.
+func (cli *Client) staleConn(conn net.Conn) bool {
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	ac, ok := conn.(*authConn)
+	return ok && ac.gen < cli.authGen.Load()
+}
+
+// retireStaleConn returns req with a trace that marks the request "Connection:
+// close" if it is assigned a stale connection. The header makes the transport
+// stop reusing the connection, including an HTTP/2 connection that other
+// requests are still multiplexed on, so it is closed once they complete.
+func (cli *Client) retireStaleConn(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if cli.staleConn(info.Conn) {
+				req.Header.Set("Connection", "close")
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// clientCertificate returns the current client certificate, or an empty
+// certificate if none is set, in which case no certificate is sent.
+func (cli *Client) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert := cli.cert.Load(); cert != nil {
+		return cert, nil
+	}
+	return &tls.Certificate{}, nil
+}
+
+// SetCertificate switches the client to certificate-based authentication with
+// cert, or rotates the certificate of a certificate-based client. The client and
+// its transport are kept and idle connections are closed, so that new connections
+// are established with the new certificate. Requests in flight complete with the
+// previous certificate. A connection that is still busy at the time of the call
+// is retired: the next request assigned to it is the last one it serves, and it
+// is closed once its requests complete.
+func (cli *Client) SetCertificate(cert *tls.Certificate) error {
+	if err := validateCertificate(cert); err != nil {
+		return err
+	}
+	if !cli.certHook {
+		return errors.New("the client's transport does not support changing the certificate")
+	}
+	c := *cert
+	cli.authMu.Lock()
+	cli.cert.Store(&c)
+	cli.inner.TokenProvider = nil
+	cli.TokenBase = false
+	cli.authMu.Unlock()
+	cli.authGen.Add(1)
+	cli.inner.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// SetTokenProvider switches the client to token-based authentication with tp,
+// or replaces the token provider of a token-based client. Any client
+// certificate is dropped and the connections established with it are closed
+// and retired like those of SetCertificate, so that subsequent requests
+// authenticate with tokens only.
+func (cli *Client) SetTokenProvider(tp token.Provider) {
+	cli.authMu.Lock()
+	cli.inner.TokenProvider = tp
+	cli.TokenBase = tp != nil
+	hadCert := cli.cert.Swap(nil) != nil
+	cli.authMu.Unlock()
+	if hadCert {
+		cli.authGen.Add(1)
+		cli.inner.HTTPClient.CloseIdleConnections()
+	}
+}
+
+// tokenProvider returns the token provider of a token-based client, or nil
+// if the client uses certificate-based authentication.
+func (cli *Client) tokenProvider() token.Provider {
+	cli.authMu.RLock()
+	defer cli.authMu.RUnlock()
+	if !cli.TokenBase {
+		return nil
+	}
+	return cli.inner.TokenProvider
+}
+
+// tokenBased reports whether the client uses token-based authentication.
+func (cli *Client) tokenBased() bool {
+	cli.authMu.RLock()
+	defer cli.authMu.RUnlock()
+	return cli.TokenBase
+}
+
+func (cli *Client) do(req *http.Request) (*http.Response, error) {
+	cli.setUserAgent(req)
+	if cli.ContentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", cli.ContentType)
+	}
+	if cli.authGen.Load() > 0 {
+		req = cli.retireStaleConn(req)
+	}
+	tp := cli.tokenProvider()
+	if tp == nil {
+		return cli.inner.HTTPClient.Do(req) // certificate based, raw http client
+	}
+	if cli.inner.Trace != nil {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), cli.inner.Trace))
+	}
+	bearer, err := tp.GetToken(time.Now())
+	if err != nil {
+		return nil, &AuthError{Err: err}
+	}
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	return cli.inner.HTTPClient.Do(req)
+}
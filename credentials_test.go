@@ -0,0 +1,123 @@
+package apns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestClient_SetCertificate(t *testing.T) {
+	var mu sync.Mutex
+	var peerCert []byte
+	var authHeader string
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/busy-device-token") {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		mu.Lock()
+		peerCert = nil
+		if len(r.TLS.PeerCertificates) > 0 {
+			peerCert = r.TLS.PeerCertificates[0].Raw
+		}
+		authHeader = r.Header.Get("Authorization")
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	certA, certB := createCert(t), createCert(t)
+	client, err := NewClientWithCert(certA)
+	if err != nil {
+		t.Fatalf("NewClientWithCert failed: %v", err)
+	}
+	client.inner.Host = server.URL
+	client.inner.HTTPClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+
+	newNotification := func(token string) *Notification {
+		return &Notification{
+			BundleID:    "com.example.app",
+			DeviceToken: token,
+			Type:        notification.Alert,
+			Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+		}
+	}
+	push := func() {
+		t.Helper()
+		if _, err := client.Push(context.Background(), newNotification("test-device-token")); err != nil {
+			t.Fatalf("Client.Push failed: %v", err)
+		}
+	}
+	// pushTwice pushes twice after a change of credentials: the first request
+	// may still be assigned a connection established with the previous
+	// credentials, which retires it, so the second must use a new connection.
+	pushTwice := func() {
+		t.Helper()
+		push()
+		push()
+	}
+
+	push()
+	if !bytes.Equal(peerCert, certA.Certificate[0]) {
+		t.Fatal("Expected the first certificate to be presented")
+	}
+
+	// Keep the connection busy with a request in flight across the rotation,
+	// so that closing idle connections does not close it.
+	busy := make(chan error, 1)
+	go func() {
+		_, err := client.Push(context.Background(), newNotification("busy-device-token"))
+		busy <- err
+	}()
+	<-started
+	if err := client.SetCertificate(certB); err != nil {
+		t.Fatalf("SetCertificate failed: %v", err)
+	}
+	push() // multiplexed on the busy connection, which it retires
+	close(release)
+	if err := <-busy; err != nil {
+		t.Fatalf("Client.Push of the busy request failed: %v", err)
+	}
+	push()
+	if !bytes.Equal(peerCert, certB.Certificate[0]) {
+		t.Error("Expected the rotated certificate to be presented")
+	}
+
+	client.SetTokenProvider(&MockTokenProvider{Token: "rotated-token"})
+	if !client.TokenBase {
+		t.Error("Expected the client to be token based")
+	}
+	pushTwice()
+	if peerCert != nil {
+		t.Error("Expected no client certificate after switching to tokens")
+	}
+	if authHeader != "Bearer rotated-token" {
+		t.Errorf("Expected bearer token, got %q", authHeader)
+	}
+
+	if err := client.SetCertificate(certA); err != nil {
+		t.Fatalf("SetCertificate failed: %v", err)
+	}
+	pushTwice()
+	if !bytes.Equal(peerCert, certA.Certificate[0]) || authHeader != "" {
+		t.Error("Expected certificate authentication without a token")
+	}
+
+	if err := client.SetCertificate(&tls.Certificate{}); err == nil {
+		t.Error("Expected an error for an invalid certificate")
+	}
+}
@@ -0,0 +1,53 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupCache remembers the notifications sent within the client's DedupWindow.
+// Entries are keyed by device token, or channel ID, and apns-id and hold their expiry time.
+type dedupCache struct {
+	mu        sync.Mutex
+	expires   map[string]time.Time
+	nextSweep time.Time
+}
+
+func dedupKey(n *Notification) string {
+	if n.ChannelID != "" {
+		return "channel:" + n.ChannelID + "/" + n.APNsID
+	}
+	return n.DeviceToken + "/" + n.APNsID
+}
+
+// reserve records key as sent until now+window. It reports false if the key
+// was already recorded and has not expired yet.
+func (c *dedupCache) reserve(key string, now time.Time, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expires == nil {
+		c.expires = make(map[string]time.Time)
+	}
+	if now.After(c.nextSweep) {
+		for k, exp := range c.expires {
+			if !now.Before(exp) {
+				delete(c.expires, k)
+			}
+		}
+		c.nextSweep = now.Add(window)
+	}
+	if exp, ok := c.expires[key]; ok && now.Before(exp) {
+		return false
+	}
+	c.expires[key] = now.Add(window)
+	return true
+}
+
+// release forgets key so that a failed send can be retried within the window.
+func (c *dedupCache) release(key string) {
+	c.mu.Lock()
+	delete(c.expires, key)
+	c.mu.Unlock()
+}
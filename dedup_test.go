@@ -0,0 +1,105 @@
+package apns
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestClient_Push_Deduplicated(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Apns-Id": []string{r.Header.Get("apns-id")}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+	client := newTestClient(t, transport)
+	client.DedupWindow = time.Minute
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		APNsID:      "123e4567-e89b-12d3-a456-4266554400a0",
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+
+	first, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if first.Deduplicated {
+		t.Errorf("first response must not be deduplicated")
+	}
+
+	second, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if !second.Deduplicated {
+		t.Errorf("second response must be deduplicated")
+	}
+	if second.APNsID != n.APNsID {
+		t.Errorf("Expected APNsID %s, got %s", n.APNsID, second.APNsID)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Expected 1 request to reach the server, got %d", got)
+	}
+
+	// A different device token is not a duplicate.
+	other := n.Clone()
+	other.DeviceToken = "other-device-token"
+	third, err := client.Push(context.Background(), other)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if third.Deduplicated {
+		t.Errorf("push to a different token must not be deduplicated")
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", got)
+	}
+}
+
+func TestClient_Push_DedupReleasedOnFailure(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if hits.Add(1) == 1 {
+			return newResponse(http.StatusServiceUnavailable, `{"reason":"ServiceUnavailable"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	client.DedupWindow = time.Minute
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		APNsID:      "123e4567-e89b-12d3-a456-4266554400a0",
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err == nil {
+		t.Fatal("expected the first push to fail")
+	}
+	res, err := client.Push(context.Background(), n)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if res.Deduplicated {
+		t.Errorf("retry after a failure must not be deduplicated")
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", got)
+	}
+}
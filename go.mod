@@ -6,11 +6,11 @@ require (
 	github.com/google/go-cmp v0.7.0
 	github.com/google/uuid v1.6.0
 	github.com/takimoto3/appleapi-core v1.1.2
+	golang.org/x/net v0.47.0
 	software.sslmate.com/src/go-pkcs12 v0.6.0
 )
 
 require (
 	golang.org/x/crypto v0.45.0 // indirect
-	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
 )
@@ -0,0 +1,30 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+// goAwayMessage is the start of the message of the error returned when the
+// server closes the connection with a GOAWAY frame while a request is in flight.
+const goAwayMessage = "http2: server sent GOAWAY and closed the connection"
+
+// isGoAway reports whether the request failed because APNs closed the HTTP/2
+// connection with a GOAWAY frame, asking the client to reconnect. Requests that
+// APNs had not started processing are already retried by the HTTP/2 transport,
+// so the error does not tell whether the request was processed.
+func isGoAway(err error) bool {
+	if err == nil {
+		return false
+	}
+	var goAway http2.GoAwayError
+	if errors.As(err, &goAway) {
+		return true
+	}
+	// net/http bundles its own copy of the HTTP/2 transport, whose GoAwayError
+	// type is not exported.
+	return strings.Contains(err.Error(), goAwayMessage)
+}
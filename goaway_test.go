@@ -0,0 +1,95 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+	"golang.org/x/net/http2"
+)
+
+func TestClient_PushMulti_GoAway(t *testing.T) {
+	goAway := errors.New(`http2: server sent GOAWAY and closed the connection; LastStreamID=3, ErrCode=NO_ERROR, debug=""`)
+	testCases := map[string]struct {
+		err         error
+		retryGoAway bool
+		wantRetry   bool
+	}{
+		"x/net GoAwayError": {
+			err:         http2.GoAwayError{LastStreamID: 3, ErrCode: http2.ErrCodeNo},
+			retryGoAway: true,
+			wantRetry:   true,
+		},
+		"net/http GOAWAY": {
+			err:         goAway,
+			retryGoAway: true,
+			wantRetry:   true,
+		},
+		"GOAWAY without RetryGoAway": {
+			err:       goAway,
+			wantRetry: false,
+		},
+		"other transport error": {
+			err:         errors.New("connection reset by peer"),
+			retryGoAway: true,
+			wantRetry:   false,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := map[string]int{}
+			transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				token := strings.TrimPrefix(r.URL.Path, Path)
+				mu.Lock()
+				attempts[token]++
+				attempt := attempts[token]
+				mu.Unlock()
+				if token == "token-2" && attempt == 1 {
+					return nil, tc.err
+				}
+				return newResponse(http.StatusOK, ""), nil
+			})
+			client := newTestClient(t, transport)
+			client.RetryGoAway = tc.retryGoAway
+
+			n := &Notification{
+				BundleID: "com.example.app",
+				Type:     notification.Alert,
+				Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+			}
+			responses, err := client.PushMulti(context.Background(), n, []string{"token-1", "token-2", "token-3"})
+
+			if tc.wantRetry {
+				if err != nil {
+					t.Fatalf("PushMulti failed: %v", err)
+				}
+				if len(responses) != 3 {
+					t.Errorf("Expected 3 responses, got %d", len(responses))
+				}
+				if attempts["token-2"] != 2 {
+					t.Errorf("Expected token-2 to be sent twice, got %d", attempts["token-2"])
+				}
+			} else {
+				var multiErr *MultiError
+				if !errors.As(err, &multiErr) || multiErr.Len() != 1 || multiErr.Failures["token-2"] == nil {
+					t.Fatalf("Expected a single failure for token-2, got %v", err)
+				}
+				if attempts["token-2"] != 1 {
+					t.Errorf("Expected token-2 to be sent once, got %d", attempts["token-2"])
+				}
+			}
+			for _, token := range []string{"token-1", "token-3"} {
+				if attempts[token] != 1 {
+					t.Errorf("Expected %s to be sent once, got %d", token, attempts[token])
+				}
+			}
+		})
+	}
+}
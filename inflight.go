@@ -0,0 +1,89 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// WithMaxInFlightBytes returns an option that limits the total size of the
+// request bodies being sent at the same time to n bytes. A send that would
+// exceed the limit blocks until enough earlier sends complete, or until its
+// context is done. Sends are admitted in the order they arrive; a single body
+// larger than n is sent on its own.
+func WithMaxInFlightBytes(n int64) Option {
+	return func(cli *Client) error {
+		if n <= 0 {
+			return errors.New("maximum in-flight bytes must be positive")
+		}
+		cli.inFlight = &byteLimiter{max: n}
+		return nil
+	}
+}
+
+// byteLimiter is a FIFO counting semaphore over bytes.
+type byteLimiter struct {
+	mu      sync.Mutex
+	max     int64
+	used    int64
+	waiters []*byteWaiter
+}
+
+type byteWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// acquire blocks until n bytes are available or ctx is done, and returns the
+// number of bytes acquired, which must be passed to release.
+func (l *byteLimiter) acquire(ctx context.Context, n int64) (int64, error) {
+	n = min(n, l.max)
+	l.mu.Lock()
+	if len(l.waiters) == 0 && l.used+n <= l.max {
+		l.used += n
+		l.mu.Unlock()
+		return n, nil
+	}
+	w := &byteWaiter{n: n, ready: make(chan struct{})}
+	l.waiters = append(l.waiters, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return n, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Acquired while giving up; hand the bytes back.
+			l.used -= n
+		default:
+			l.waiters = slices.DeleteFunc(l.waiters, func(x *byteWaiter) bool { return x == w })
+		}
+		l.notify()
+		return 0, ctx.Err()
+	}
+}
+
+// release returns n bytes and wakes the waiters that now fit, in order.
+func (l *byteLimiter) release(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.used -= n
+	l.notify()
+}
+
+func (l *byteLimiter) notify() {
+	for len(l.waiters) > 0 {
+		w := l.waiters[0]
+		if l.used+w.n > l.max {
+			return
+		}
+		l.used += w.n
+		close(w.ready)
+		l.waiters = l.waiters[1:]
+	}
+}
@@ -0,0 +1,134 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+	"github.com/takimoto3/appleapi-core"
+)
+
+func TestWithMaxInFlightBytes(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight, bytes, maxBytes int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		inFlight++
+		bytes += len(body)
+		maxInFlight = max(maxInFlight, inFlight)
+		maxBytes = max(maxBytes, bytes)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		bytes -= len(body)
+		mu.Unlock()
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	const limit = 4000
+	if err := client.Configure(WithMaxInFlightBytes(limit)); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+
+	push := func(alert string) {
+		t.Helper()
+		mu.Lock()
+		maxInFlight, maxBytes = 0, 0
+		mu.Unlock()
+		n := &Notification{
+			BundleID: "com.example.app",
+			Type:     notification.Alert,
+			Payload:  &Payload{APS: payload.APS{Alert: alert}},
+		}
+		items := make([]BatchItem, 10)
+		for i := range items {
+			items[i] = BatchItem{Token: fmt.Sprintf("token-%d", i), Notification: n}
+		}
+		if _, err := client.PushBatch(context.Background(), items); err != nil {
+			t.Fatalf("PushBatch failed: %v", err)
+		}
+	}
+
+	// Bodies of about 1800 bytes: only two fit within the limit at a time.
+	push(strings.Repeat("x", 1800))
+	if maxBytes > limit {
+		t.Errorf("Expected at most %d bytes in flight, got %d", limit, maxBytes)
+	}
+	if maxInFlight != 2 {
+		t.Errorf("Expected 2 large requests in flight at most, got %d", maxInFlight)
+	}
+
+	// Small bodies are not limited by count.
+	push("small")
+	if maxInFlight <= 2 {
+		t.Errorf("Expected more than 2 small requests in flight, got %d", maxInFlight)
+	}
+
+	// A body larger than the limit is still sent, on its own.
+	client = newTestClient(t, transport)
+	if err := client.Configure(WithMaxInFlightBytes(1000)); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	push(strings.Repeat("x", 1800))
+	if maxInFlight != 1 {
+		t.Errorf("Expected oversized requests to be sent one at a time, got %d", maxInFlight)
+	}
+}
+
+func TestWithMaxInFlightBytes_Invalid(t *testing.T) {
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	for _, n := range []int64{0, -1} {
+		if err := client.Configure(WithMaxInFlightBytes(n)); err == nil {
+			t.Errorf("WithMaxInFlightBytes(%d): expected an error", n)
+		}
+	}
+}
+
+func TestByteLimiter_ContextCanceled(t *testing.T) {
+	l := &byteLimiter{max: 100}
+	held, err := l.acquire(context.Background(), 80)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 50); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// The canceled waiter must not block later ones.
+	acquired := make(chan struct{})
+	go func() {
+		n, err := l.acquire(context.Background(), 20)
+		if err == nil {
+			l.release(n)
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a fitting acquire to succeed after a canceled waiter")
+	}
+
+	l.release(held)
+	if l.used != 0 || len(l.waiters) != 0 {
+		t.Errorf("Expected the limiter to be empty, got used=%d waiters=%d", l.used, len(l.waiters))
+	}
+}
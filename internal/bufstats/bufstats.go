@@ -0,0 +1,23 @@
+// Package bufstats records the sizes of the pooled marshaling buffers of the
+// apns and payload packages, which payload.BufferHighWaterMark reports.
+package bufstats
+
+import "sync/atomic"
+
+var highWaterMark atomic.Int64
+
+// HighWaterMark returns the largest buffer size recorded by Observe.
+func HighWaterMark() int {
+	return int(highWaterMark.Load())
+}
+
+// Observe records the size of a pooled marshaling buffer. It is called by the
+// fast encoders when a buffer is returned to its pool.
+func Observe(n int) {
+	for {
+		cur := highWaterMark.Load()
+		if int64(n) <= cur || highWaterMark.CompareAndSwap(cur, int64(n)) {
+			return
+		}
+	}
+}
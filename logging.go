@@ -0,0 +1,80 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WithLogger returns an option that sets the logger used to log requests and failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cli *Client) error {
+		cli.Logger = logger
+		return nil
+	}
+}
+
+// redactToken keeps the first characters of a device token and hides the rest,
+// which is enough to correlate log lines without exposing the token.
+func redactToken(token string) string {
+	const visible = 8
+	if len(token) <= visible {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:visible] + "..."
+}
+
+// logRequest logs a request at debug level. The authorization header is never logged.
+func (cli *Client) logRequest(req *http.Request) {
+	if cli.Logger == nil || !cli.Logger.Enabled(req.Context(), slog.LevelDebug) {
+		return
+	}
+	cli.Logger.LogAttrs(req.Context(), slog.LevelDebug, "sending APNs request",
+		slog.String("method", req.Method),
+		slog.String("path", redactedPath(req.URL.Path)),
+		slog.String("push_type", req.Header.Get("apns-push-type")),
+		slog.String("apns_id", req.Header.Get("apns-id")),
+	)
+}
+
+// logFailure logs a failed request: APNs rejections with status 4xx at warn
+// level, and server errors and transport failures at error level.
+func (cli *Client) logFailure(ctx context.Context, req *http.Request, status int, err error, latency time.Duration) {
+	if cli.Logger == nil || err == nil {
+		return
+	}
+	level := slog.LevelError
+	if status >= 400 && status < 500 {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("path", redactedPath(req.URL.Path)),
+		slog.String("push_type", req.Header.Get("apns-push-type")),
+		slog.Int("status", status),
+		slog.Duration("latency", latency),
+	}
+	var apnsErr *Error
+	if errors.As(err, &apnsErr) {
+		attrs = append(attrs, slog.String("reason", apnsErr.Reason))
+	}
+	// Transport errors include the request URL, and with it the device token.
+	message := err.Error()
+	if token, ok := strings.CutPrefix(req.URL.Path, Path); ok && token != "" {
+		message = strings.ReplaceAll(message, url.PathEscape(token), redactToken(token))
+		message = strings.ReplaceAll(message, token, redactToken(token))
+	}
+	attrs = append(attrs, slog.String("error", message))
+	cli.Logger.LogAttrs(ctx, level, "APNs request failed", attrs...)
+}
+
+func redactedPath(path string) string {
+	if token, ok := strings.CutPrefix(path, Path); ok {
+		return Path + redactToken(token)
+	}
+	return path
+}
@@ -0,0 +1,156 @@
+package apns
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+	"github.com/takimoto3/appleapi-core"
+)
+
+// captureHandler is a slog.Handler that records every log record.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler      { return h }
+
+func recordAttrs(r slog.Record) map[string]string {
+	attrs := map[string]string{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	return attrs
+}
+
+func TestClient_Logger(t *testing.T) {
+	const goodToken = "0123456789abcdef0123456789abcdef"
+	const badToken = "fedcba9876543210fedcba9876543210"
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, badToken) {
+			return newResponse(http.StatusBadRequest, `{"reason":"BadDeviceToken"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "secret-token"}, appleapi.WithTransport(transport))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	handler := &captureHandler{}
+	if err := client.Configure(WithLogger(slog.New(handler))); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: goodToken,
+		Type:        notification.Alert,
+		APNsID:      "123e4567-e89b-12d3-a456-4266554400a0",
+		Payload:     &Payload{APS: payload.APS{Alert: "hello"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	n.DeviceToken = badToken
+	if _, err := client.Push(context.Background(), n); err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if len(handler.records) != 3 {
+		t.Fatalf("Expected 3 log records, got %d", len(handler.records))
+	}
+	debug := handler.records[0]
+	if debug.Level != slog.LevelDebug {
+		t.Errorf("record 0: level = %v, want %v", debug.Level, slog.LevelDebug)
+	}
+	want := map[string]string{
+		"method":    http.MethodPost,
+		"path":      Path + "01234567...",
+		"push_type": "alert",
+		"apns_id":   n.APNsID,
+	}
+	attrs := recordAttrs(debug)
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("record 0: %s = %q, want %q", k, attrs[k], v)
+		}
+	}
+
+	failure := handler.records[2]
+	if failure.Level != slog.LevelWarn {
+		t.Errorf("record 2: level = %v, want %v", failure.Level, slog.LevelWarn)
+	}
+	attrs = recordAttrs(failure)
+	if attrs["status"] != "400" || attrs["reason"] != "BadDeviceToken" || attrs["path"] != Path+"fedcba98..." {
+		t.Errorf("record 2: unexpected attributes %v", attrs)
+	}
+
+	for i, r := range handler.records {
+		for k, v := range recordAttrs(r) {
+			if strings.Contains(v, goodToken) || strings.Contains(v, badToken) || strings.Contains(v, "secret-token") {
+				t.Errorf("record %d: attribute %s leaks a secret: %q", i, k, v)
+			}
+		}
+	}
+}
+
+func TestClient_Logger_TransportError(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+	client, err := NewClient(appleapi.DefaultHTTPClientInitializer(), &MockTokenProvider{Token: "secret-token"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.inner.Host = "http://127.0.0.1:0" // nothing listens on port 0
+	handler := &captureHandler{}
+	client.Logger = slog.New(handler)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: token,
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "hello"}},
+	}
+	if _, err := client.Push(context.Background(), n); err == nil || !strings.Contains(err.Error(), token) {
+		t.Fatalf("Expected a transport error mentioning the request URL, got %v", err)
+	}
+
+	failure := handler.records[len(handler.records)-1]
+	if failure.Level != slog.LevelError {
+		t.Errorf("level = %v, want %v", failure.Level, slog.LevelError)
+	}
+	attrs := recordAttrs(failure)
+	if strings.Contains(attrs["error"], token) || !strings.Contains(attrs["error"], "01234567...") {
+		t.Errorf("error attribute = %q, want the device token redacted", attrs["error"])
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"short":              "*****",
+		"0123456789abcdef":   "01234567...",
+		"0123456789abcdefgh": "01234567...",
+	}
+	for in, want := range tests {
+		if got := redactToken(in); got != want {
+			t.Errorf("redactToken(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -4,10 +4,15 @@ package apns
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"net/textproto"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/notification/priority"
+	"github.com/takimoto3/apns/payload"
 )
 
 // Notification represents a complete APNs notification request.
@@ -23,29 +28,56 @@ type Notification struct {
 	Type notification.PushType
 
 	// APNsID is a canonical UUID that identifies the notification.
-	// If you omit this, a new UUID is generated by APNs and returned in the response.
+	// If you omit this, a new UUID is generated by APNs and returned in the response,
+	// or by the client if Client.AutoGenerateAPNsID is set. A non-empty APNsID is
+	// always sent as is.
 	// Corresponds to the `apns-id` header.
 	APNsID string
 
 	// Expiration specifies the time at which the notification is no longer valid.
-	// This corresponds to the `apns-expiration` header.
-	// A zero value means the notification expires immediately.
+	// This corresponds to the `apns-expiration` header. Omitting it and setting it
+	// to zero are very different:
+	//   - nil omits the header, and APNs stores the notification and retries
+	//     delivery for a period of its choosing if the device is offline.
+	//   - zero (notification.ExpirationOnce, or SetExpireImmediately) sends "0",
+	//     and APNs attempts delivery once and discards the notification if the
+	//     device cannot be reached.
+	//
+	// Any other value makes APNs retry delivery until that time; see
+	// notification.ExpireIn.
 	Expiration *notification.EpochTime
 
 	// Priority is the priority of the notification.
-	// This corresponds to the `apns-priority` header.
+	// This corresponds to the `apns-priority` header, which is only omitted for
+	// priority.None. Any other value must be Valid.
 	Priority priority.Priority
 
 	// CollapseID is an identifier used to group related notifications.
 	// This corresponds to the `apns-collapse-id` header.
+	// It must not exceed MaxCollapseIDSize (64) bytes.
 	CollapseID string
 
 	// DeviceToken is the hexadecimal string that uniquely identifies the device.
 	// This is part of the request URL.
 	DeviceToken string
 
+	// ChannelID identifies the broadcast channel of a Live Activity. If set, the
+	// notification is broadcast to all subscribers of the channel instead of being
+	// sent to a device, so DeviceToken must be empty and Type must be
+	// `liveactivity`. This corresponds to the `apns-channel-id` header.
+	ChannelID string
+
 	// Payload is the JSON payload of the notification.
 	Payload *Payload
+
+	// Headers holds additional HTTP headers to send with the request, such as a
+	// correlation ID for proxies or tracing. Reserved headers (`apns-*`,
+	// `authorization`, `host`, `content-type` and `content-length`) cannot be set here.
+	Headers map[string]string
+
+	// body is set by Template and shared by the copies WithToken makes of the
+	// template, which marshal their payload once.
+	body *sharedBody
 }
 
 // Topic returns the appropriate `apns-topic` header value based on the notification's
@@ -53,44 +85,79 @@ type Notification struct {
 //
 // For most push types, the topic is simply the BundleID. For special types like
 // `voip`, `complication`, or `liveactivity`, a specific suffix is appended to the
-// BundleID as required by APNs.
+// BundleID as required by APNs. The suffix is appended even if the BundleID
+// already ends with it, which strict validation reports.
 func (n Notification) Topic() string {
-	bundleID := n.BundleID
-	switch n.Type {
-	case notification.Alert, notification.Background:
-		return bundleID
-	case notification.Complication:
-		return bundleID + ".complication"
-	case notification.Controls:
-		return bundleID + ".push-type.controls"
-	case notification.Fileprovider:
-		return bundleID + ".pushkit.fileprovider"
-	case notification.Liveactivity:
-		return bundleID + ".push-type.liveactivity"
-	case notification.Location:
-		return bundleID + ".location-query"
-	case notification.Mdm:
-		return bundleID
-	case notification.Pushtotalk:
-		return bundleID + ".voip-ptt"
-	case notification.Voip:
-		return bundleID + ".voip"
-	case notification.Widgets:
-		return bundleID + ".push-type.widgets"
-	default:
-		return bundleID // fallback for unknown types
+	return n.BundleID + notification.TopicSuffix(n.Type)
+}
+
+// CollapseKey returns the key under which APNs groups the notification for
+// collapsing: the topic and the CollapseID, separated by a slash. On a given
+// device, notifications with the same key collapse into one. It returns an
+// empty string if the notification has no CollapseID and so never collapses.
+//
+// The key is informational only; it is not sent to APNs.
+func (n Notification) CollapseKey() string {
+	if n.CollapseID == "" {
+		return ""
 	}
+	return n.Topic() + "/" + n.CollapseID
 }
 
 // Validate checks if the notification is well-formed before sending it.
 // It validates the presence of required fields like BundleID, DeviceToken, and Type.
 // It also checks the format of APNsID (if present) and the validity of other fields.
 func (n *Notification) Validate() error {
+	if err := n.validateHeaders(); err != nil {
+		return err
+	}
+
+	// Validate Payload presence for specific push types
+	if n.Type == notification.Alert || n.Type == notification.Background || n.Type == notification.Liveactivity {
+		if n.Payload == nil {
+			return fmt.Errorf("Payload is required for %s push type", n.Type)
+		}
+	}
+
+	if n.Type == notification.Background && n.Payload != nil {
+		if err := validateBackground(&n.Payload.APS); err != nil {
+			return err
+		}
+	}
+
+	if n.Type == notification.Liveactivity && n.Payload != nil {
+		if err := validateLiveActivity(&n.Payload.APS); err != nil {
+			return err
+		}
+	}
+
+	if n.Type == notification.Voip {
+		if err := validateVoIP(n); err != nil {
+			return err
+		}
+	}
+
+	if n.Payload != nil {
+		if err := n.Payload.APS.Validate(); err != nil {
+			return err
+		}
+		// CustomData is merged at the root of the payload, next to "aps".
+		if _, ok := n.Payload.CustomData["aps"]; ok {
+			return fmt.Errorf("invalid CustomData: %w", ErrReservedCustomKey)
+		}
+	}
+
+	return nil
+}
+
+// validateHeaders validates the fields of the notification that are sent as
+// the request path and headers, leaving out the payload.
+func (n *Notification) validateHeaders() error {
 	if n.BundleID == "" {
 		return errors.New("BundleID is required")
 	}
 	// Validate DeviceToken (non-empty only)
-	if n.DeviceToken == "" {
+	if n.DeviceToken == "" && n.ChannelID == "" {
 		return errors.New("DeviceToken is required")
 	}
 
@@ -108,37 +175,273 @@ func (n *Notification) Validate() error {
 		return fmt.Errorf("invalid apns-push-type: %s", n.Type)
 	}
 
+	if n.ChannelID != "" {
+		if n.DeviceToken != "" {
+			return errors.New("DeviceToken and ChannelID are mutually exclusive")
+		}
+		if n.Type != notification.Liveactivity {
+			return fmt.Errorf("ChannelID requires the liveactivity push type, got %s", n.Type)
+		}
+	}
+
 	if n.APNsID != "" {
 		if _, err := uuid.Parse(n.APNsID); err != nil {
 			return fmt.Errorf("invalid APNsID: %w", err)
 		}
 	}
 
+	if len(n.CollapseID) > MaxCollapseIDSize {
+		return fmt.Errorf("CollapseID must not exceed %d bytes, got %d", MaxCollapseIDSize, len(n.CollapseID))
+	}
+
 	// Validate Priority
-	switch n.Priority {
-	case priority.None, priority.PowerOnly, priority.Conserve, priority.Immediate:
-		// Valid Priority
-	default:
+	if !n.Priority.Valid() {
 		return fmt.Errorf("invalid apns-priority: %d", n.Priority)
 	}
+	// APNs rejects background notifications sent with priority 10.
+	if n.Type == notification.Background && n.Priority == priority.Immediate {
+		return fmt.Errorf("apns-priority %d is not allowed for the background push type; use priority.Conserve (5)", n.Priority)
+	}
 
-	// Validate Payload presence for specific push types
-	if n.Type == notification.Alert || n.Type == notification.Background {
-		if n.Payload == nil {
-			return fmt.Errorf("Payload is required for %s push type", n.Type)
+	for name := range n.Headers {
+		if isReservedHeader(name) {
+			return fmt.Errorf("header %q is reserved and cannot be set in Headers", name)
 		}
 	}
 
-	if n.Payload != nil {
-		if err := n.Payload.APS.Validate(); err != nil {
-			return err
+	return nil
+}
+
+// isReservedHeader reports whether name is managed by the client and therefore
+// must not be overridden by Notification.Headers.
+func isReservedHeader(name string) bool {
+	name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+	if name == "" || strings.HasPrefix(name, ":") || strings.HasPrefix(name, "Apns-") {
+		return true
+	}
+	switch name {
+	case "Authorization", "Host", "Content-Type", "Content-Length":
+		return true
+	}
+	return false
+}
+
+// ValidateStrict performs the checks of Validate and, if they pass, additional
+// advisory checks for notifications that APNs accepts but that rarely behave as
+// intended. Advisory failures are reported as *payload.ValidationError.
+//
+// In addition to the rules of payload.APS.ValidateStrict:
+//   - The BundleID must not contain uppercase letters. Topics are case-sensitive
+//     and reverse-DNS bundle IDs are conventionally lowercase, so uppercase letters
+//     are usually a copy-paste error.
+//   - A notification with ContentAvailable set and no alert, badge or sound must
+//     use the `background` push type, since it is a silent push.
+//   - The Expiration must not be more than DefaultMaxExpiration in the future,
+//     which usually means it was given in milliseconds instead of seconds.
+func (n *Notification) ValidateStrict() error {
+	return n.validateStrict(DefaultMaxExpiration)
+}
+
+func (n *Notification) validateStrict(maxExpiration time.Duration) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if err := n.ValidateExpiration(maxExpiration); err != nil {
+		return err
+	}
+	if err := n.validateStrictTopic(); err != nil {
+		return err
+	}
+	if n.Payload == nil {
+		return nil
+	}
+	aps := &n.Payload.APS
+	if err := aps.ValidateStrict(); err != nil {
+		return err
+	}
+	silent := aps.ContentAvailable != nil && aps.Alert == nil && aps.Badge == nil && aps.Sound == nil
+	if silent && n.Type != notification.Background {
+		return &payload.ValidationError{
+			Field:   "apns-push-type",
+			Code:    payload.CodeBackgroundPushType,
+			Message: fmt.Sprintf("content-available without user-facing content should use the background push type, got %s", n.Type),
 		}
 	}
+	return nil
+}
 
+// validateStrictTopic applies the strict rules to the BundleID, from which the
+// apns-topic header is derived.
+func (n *Notification) validateStrictTopic() error {
+	if strings.ToLower(n.BundleID) != n.BundleID {
+		return &payload.ValidationError{
+			Field:   "apns-topic",
+			Code:    payload.CodeUppercaseBundleID,
+			Message: fmt.Sprintf("BundleID %q contains uppercase letters; bundle IDs are case-sensitive", n.BundleID),
+		}
+	}
+	if suffix := notification.TopicSuffix(n.Type); suffix != "" && strings.HasSuffix(n.BundleID, suffix) {
+		return &payload.ValidationError{
+			Field:   "apns-topic",
+			Code:    payload.CodeDoubledTopicSuffix,
+			Message: fmt.Sprintf("BundleID %q already ends with the %s topic suffix %q, which the topic would repeat; use the bare bundle ID", n.BundleID, n.Type, suffix),
+		}
+	}
 	return nil
 }
 
+// ValidateExpiration reports a *payload.ValidationError if the Expiration is more
+// than max in the future. APNs stores notifications for a limited time, so a far
+// future expiration usually indicates a unit error, such as passing milliseconds
+// where seconds are expected. A nil Expiration always passes.
+func (n *Notification) ValidateExpiration(max time.Duration) error {
+	if n.Expiration == nil {
+		return nil
+	}
+	limit := time.Now().Add(max).Unix()
+	if int64(*n.Expiration) > limit {
+		return &payload.ValidationError{
+			Field:   "apns-expiration",
+			Code:    payload.CodeExpirationTooFar,
+			Message: fmt.Sprintf("apns-expiration %d is more than %s in the future; is it in milliseconds instead of seconds?", *n.Expiration, max),
+		}
+	}
+	return nil
+}
+
+// validateVoIP catches obvious misuse of the voip push type: a BundleID that
+// already carries the topic suffix of another push type, e.g. ".voip-ptt",
+// which would be sent with both suffixes, and a silent content-available
+// payload, which belongs to background pushes since a VoIP push must report
+// an incoming call.
+func validateVoIP(n *Notification) error {
+	for _, t := range []notification.PushType{
+		notification.Complication, notification.Controls, notification.Fileprovider, notification.Liveactivity,
+		notification.Location, notification.Pushtotalk, notification.Widgets,
+	} {
+		if suffix := notification.TopicSuffix(t); strings.HasSuffix(n.BundleID, suffix) {
+			return &payload.ValidationError{
+				Field:   "apns-topic",
+				Code:    payload.CodeTopicSuffixMismatch,
+				Message: fmt.Sprintf("BundleID %q ends with the %s topic suffix %q; use the bare bundle ID for the voip push type", n.BundleID, t, suffix),
+			}
+		}
+	}
+	if n.Payload != nil && n.Payload.APS.ContentAvailable != nil {
+		return &payload.ValidationError{
+			Field:   "content-available",
+			Code:    payload.CodeVoIPContentAvailable,
+			Message: "content-available must not be set for the voip push type; use the background push type for silent updates",
+		}
+	}
+	return nil
+}
+
+// validateBackground checks that a background push carries no user-facing
+// content. An alert, sound or badge makes the notification user-visible, so it
+// must be sent with the alert push type instead.
+func validateBackground(aps *payload.APS) error {
+	var field string
+	switch {
+	case aps.Alert != nil:
+		field = "alert"
+	case aps.Sound != nil:
+		field = "sound"
+	case aps.Badge != nil:
+		field = "badge"
+	default:
+		return nil
+	}
+	return &payload.ValidationError{
+		Field:   field,
+		Code:    payload.CodeBackgroundUserContent,
+		Message: fmt.Sprintf("background push type must not include %s; use the alert push type for user-visible notifications", field),
+	}
+}
+
+// validateLiveActivity checks the requirements of a `liveactivity` push that
+// depend on the Live Activity event: the event is required, `start` needs the
+// attributes and their type, and `update` and `end` need the content state.
+// Failures are reported as *payload.ValidationError. Unknown events are left to
+// APS.Validate.
+func validateLiveActivity(aps *payload.APS) error {
+	missing := func(field, code string) error {
+		return &payload.ValidationError{
+			Field:   field,
+			Code:    code,
+			Message: fmt.Sprintf("%s is required for Live Activity %s events", field, aps.Event),
+		}
+	}
+	switch aps.Event {
+	case "":
+		return &payload.ValidationError{
+			Field:   "event",
+			Code:    payload.CodeRequired,
+			Message: "event is required for the liveactivity push type",
+		}
+	case "start":
+		if aps.AttributesType == "" {
+			return missing("attributes-type", payload.CodeRequiredForStart)
+		}
+		if len(aps.Attributes) == 0 {
+			return missing("attributes", payload.CodeRequiredForStart)
+		}
+	case "update":
+		if len(aps.ContentState) == 0 {
+			return missing("content-state", payload.CodeRequiredForUpdate)
+		}
+	case "end":
+		if len(aps.ContentState) == 0 {
+			return missing("content-state", payload.CodeRequiredForEnd)
+		}
+	}
+	return nil
+}
+
+// SetExpireImmediately sets Expiration to zero, the value of
+// notification.ExpirationOnce, so that APNs attempts delivery only once and
+// does not store the notification if the device cannot be reached. Unlike
+// assigning ExpirationOnce, it sets a value of the notification's own.
+func (n *Notification) SetExpireImmediately() {
+	n.Expiration = notification.NewEpochTime(time.Time{})
+}
+
+// WithToken returns a shallow copy of the notification with DeviceToken set to
+// token, for sending the same notification to many devices. Unlike Clone, it
+// shares the payload, headers and expiration with n, so they must not be
+// modified while the copies are in use. If n was created with Template, the
+// copies also share the marshaled payload.
+func (n *Notification) WithToken(token string) *Notification {
+	c := *n
+	c.DeviceToken = token
+	return &c
+}
+
+// Template returns a shallow copy of the notification whose payload is marshaled
+// only once for all the notifications created from it with WithToken: the first
+// one sent marshals it and the others reuse the body. The payload must therefore
+// not be modified once the template has been sent; to send a modified payload,
+// create a new template. The marshaled body is released with the template and
+// its copies.
+func (n *Notification) Template() *Notification {
+	c := *n
+	c.body = &sharedBody{}
+	return &c
+}
+
+// Clone returns a deep copy of the notification. The expiration, the headers
+// and the payload are copied, so the clone can be modified concurrently with
+// the original.
 func (n *Notification) Clone() *Notification {
 	c := *n
+	c.body = nil
+	if n.Expiration != nil {
+		exp := *n.Expiration
+		c.Expiration = &exp
+	}
+	c.Headers = maps.Clone(n.Headers)
+	if n.Payload != nil {
+		c.Payload = n.Payload.Clone()
+	}
 	return &c
 }
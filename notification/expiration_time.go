@@ -2,6 +2,7 @@
 package notification
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 )
@@ -9,6 +10,9 @@ import (
 // ExpirationOnce is a special expiration value (epoch time 0) that tells APNs
 // not to store the notification at all. APNs will make one attempt to deliver
 // the notification, and if it cannot be delivered immediately, it will be discarded.
+// Leaving the expiration nil instead omits the apns-expiration header, and APNs
+// then stores the notification for later delivery.
+// It is shared, so the value it points to must not be modified.
 var ExpirationOnce = NewEpochTime(time.Time{})
 
 // EpochTime represents a UNIX timestamp as an int64.
@@ -25,6 +29,51 @@ func NewEpochTime(t time.Time) *EpochTime {
 	return &v
 }
 
+// ExpireIn returns the EpochTime d from now, so that APNs stores the
+// notification and retries delivery for about d, e.g. ExpireIn(time.Hour).
+// Use ExpirationOnce instead for one-shot delivery without storing.
+func ExpireIn(d time.Duration) *EpochTime {
+	return NewEpochTime(time.Now().Add(d))
+}
+
+// EpochTimeFromUnix creates a new EpochTime from a UNIX timestamp in seconds,
+// e.g. one loaded from a database. It returns a pointer to the EpochTime value.
+func EpochTimeFromUnix(sec int64) *EpochTime {
+	v := EpochTime(sec)
+	return &v
+}
+
+// Time returns the UNIX timestamp as a time.Time in UTC. For a non-zero value it
+// is the inverse of NewEpochTime, truncated to whole seconds.
+func (e EpochTime) Time() time.Time {
+	return time.Unix(int64(e), 0).UTC()
+}
+
+// IsZero reports whether the timestamp is 0, which is what NewEpochTime returns
+// for the zero time.Time and what ExpirationOnce is set to.
+func (e EpochTime) IsZero() bool {
+	return e == 0
+}
+
+// MarshalJSON encodes the UNIX timestamp as a bare JSON integer.
+func (e EpochTime) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(e), 10), nil
+}
+
+// UnmarshalJSON decodes a UNIX timestamp from a JSON integer. A JSON null leaves
+// the value unchanged.
+func (e *EpochTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid epoch time %s: must be an integer number of seconds", data)
+	}
+	*e = EpochTime(v)
+	return nil
+}
+
 // String returns the string representation of the UNIX timestamp.
 func (e EpochTime) String() string {
 	return strconv.FormatInt(int64(e), 10)
@@ -1,6 +1,7 @@
 package notification_test
 
 import (
+	"encoding/json"
 	"strconv"
 	"testing"
 	"time"
@@ -73,3 +74,110 @@ func TestEpochTimeString(t *testing.T) {
 		})
 	}
 }
+
+func TestExpireIn(t *testing.T) {
+	for _, d := range []time.Duration{time.Minute, time.Hour, 24 * time.Hour} {
+		before := time.Now().Add(d).Unix()
+		e := notification.ExpireIn(d)
+		after := time.Now().Add(d).Unix()
+		if int64(*e) < before || int64(*e) > after {
+			t.Errorf("ExpireIn(%v) = %d; want between %d and %d", d, *e, before, after)
+		}
+	}
+}
+
+func TestEpochTimeFromUnix(t *testing.T) {
+	sec := int64(1698400800)
+	e := notification.EpochTimeFromUnix(sec)
+	if int64(*e) != sec {
+		t.Errorf("EpochTimeFromUnix(%d) = %d; want %d", sec, *e, sec)
+	}
+	if want := time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC); !e.Time().Equal(want) {
+		t.Errorf("EpochTime(%d).Time() = %v; want %v", sec, e.Time(), want)
+	}
+}
+
+func TestEpochTimeJSON(t *testing.T) {
+	type record struct {
+		Expiration *notification.EpochTime `json:"expiration,omitempty"`
+		StaleDate  notification.EpochTime  `json:"stale-date"`
+	}
+
+	in := record{
+		Expiration: notification.EpochTimeFromUnix(1698400800),
+		StaleDate:  notification.EpochTime(42),
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if want := `{"expiration":1698400800,"stale-date":42}`; string(b) != want {
+		t.Errorf("json.Marshal = %s; want %s", b, want)
+	}
+
+	var out record
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if out.Expiration == nil || *out.Expiration != *in.Expiration || out.StaleDate != in.StaleDate {
+		t.Errorf("json round trip = %+v; want %+v", out, in)
+	}
+
+	out = record{}
+	if err := json.Unmarshal([]byte(`{"expiration":null,"stale-date":0}`), &out); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if out.Expiration != nil {
+		t.Errorf("Expected a nil Expiration for null, got %v", *out.Expiration)
+	}
+
+	invalid := map[string]string{
+		"float":  `{"stale-date":1.5}`,
+		"string": `{"stale-date":"42"}`,
+		"bool":   `{"stale-date":true}`,
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			var r record
+			if err := json.Unmarshal([]byte(data), &r); err == nil {
+				t.Errorf("json.Unmarshal(%s) expected an error, got none", data)
+			}
+		})
+	}
+}
+
+func TestEpochTimeTime(t *testing.T) {
+	testCases := map[string]time.Time{
+		"Epoch plus one second": time.Date(1970, 1, 1, 0, 0, 1, 0, time.UTC),
+		"UTC time":              time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC),
+		"Non-UTC time":          time.Date(2024, 2, 29, 23, 30, 15, 0, time.FixedZone("JST", 9*60*60)),
+		"Before the epoch":      time.Date(1969, 7, 20, 20, 17, 40, 0, time.UTC),
+		"Sub-second precision":  time.Date(2025, 1, 1, 0, 0, 0, 999999999, time.UTC),
+	}
+
+	for name, in := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := notification.NewEpochTime(in)
+			got := e.Time()
+			want := in.Truncate(time.Second).UTC()
+			if !got.Equal(want) || got.Location() != time.UTC {
+				t.Errorf("NewEpochTime(%v).Time() = %v; want %v", in, got, want)
+			}
+			if e.IsZero() {
+				t.Errorf("NewEpochTime(%v).IsZero() = true; want false", in)
+			}
+		})
+	}
+}
+
+func TestEpochTimeIsZero(t *testing.T) {
+	if !notification.NewEpochTime(time.Time{}).IsZero() {
+		t.Error("NewEpochTime(time.Time{}).IsZero() = false; want true")
+	}
+	if !notification.ExpirationOnce.IsZero() {
+		t.Error("ExpirationOnce.IsZero() = false; want true")
+	}
+	if notification.EpochTime(1).IsZero() {
+		t.Error("EpochTime(1).IsZero() = true; want false")
+	}
+}
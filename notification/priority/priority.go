@@ -16,8 +16,20 @@ const (
 	Immediate Priority = 10
 )
 
+// Valid reports whether the priority is None or one of the priorities defined by APNs.
+func (p Priority) Valid() bool {
+	switch p {
+	case None, PowerOnly, Conserve, Immediate:
+		return true
+	default:
+		return false
+	}
+}
+
 // String returns the string representation of the priority value.
-// It returns an empty string if the priority is None (0), which signals to omit the header.
+// It returns an empty string if the priority is None (0), which signals to omit
+// the header, and also if the priority is not Valid; such priorities are
+// rejected by validation rather than sent without the header.
 func (p Priority) String() string {
 	switch p {
 	case PowerOnly, Conserve, Immediate:
@@ -42,3 +42,24 @@ func TestPriority_String(t *testing.T) {
 		})
 	}
 }
+
+func TestPriority_Valid(t *testing.T) {
+	testCases := map[string]struct {
+		priority priority.Priority
+		expected bool
+	}{
+		"None":      {priority: priority.None, expected: true},
+		"PowerOnly": {priority: priority.PowerOnly, expected: true},
+		"Conserve":  {priority: priority.Conserve, expected: true},
+		"Immediate": {priority: priority.Immediate, expected: true},
+		"Undefined": {priority: 99, expected: false},
+		"Negative":  {priority: -1, expected: false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.priority.Valid(); got != tc.expected {
+				t.Errorf("Priority(%d).Valid() = %v; want %v", tc.priority, got, tc.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 // package notification provides types related to the metadata of an APNs notification.
 package notification
 
+import "strconv"
+
 // PushType corresponds to the `apns-push-type` header field.
 type PushType = string
 
@@ -28,3 +30,42 @@ const (
 	// Widgets push type is for updating a widget's content.
 	Widgets PushType = "widgets"
 )
+
+// pushTypeInfo describes a known push type and the suffix appended to the
+// bundle ID to form its `apns-topic`.
+type pushTypeInfo struct {
+	description string
+	topicSuffix string
+}
+
+var pushTypes = map[PushType]pushTypeInfo{
+	Alert:        {"displays an alert, plays a sound, or badges the app's icon", ""},
+	Background:   {"delivers content in the background without user interaction", ""},
+	Complication: {"updates a watchOS app's complication", ".complication"},
+	Controls:     {"updates the controls of a widget", ".push-type.controls"},
+	Fileprovider: {"signals changes to a File Provider extension", ".pushkit.fileprovider"},
+	Liveactivity: {"starts, updates, or ends a Live Activity", ".push-type.liveactivity"},
+	Location:     {"requests the device's location through a Location Push Service Extension", ".location-query"},
+	Mdm:          {"tells a device enrolled in MDM to contact its MDM server", ""},
+	Pushtotalk:   {"delivers a Push to Talk notification", ".voip-ptt"},
+	Voip:         {"delivers an incoming VoIP call through PushKit", ".voip"},
+	Widgets:      {"reloads the content of a widget", ".push-type.widgets"},
+}
+
+// TopicSuffix returns the suffix appended to the bundle ID to form the
+// `apns-topic` of the push type, e.g. ".voip" for Voip. It is empty for push
+// types whose topic is the bundle ID itself and for unknown push types.
+func TopicSuffix(t PushType) string {
+	return pushTypes[t].topicSuffix
+}
+
+// DescribePushType returns a short human-readable description of the push type
+// and its topic convention, e.g.
+// "voip: delivers an incoming VoIP call through PushKit (topic: <bundle-id>.voip)".
+func DescribePushType(t PushType) string {
+	info, ok := pushTypes[t]
+	if !ok {
+		return "unknown push type " + strconv.Quote(t)
+	}
+	return t + ": " + info.description + " (topic: <bundle-id>" + info.topicSuffix + ")"
+}
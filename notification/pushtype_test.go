@@ -0,0 +1,32 @@
+package notification_test
+
+import (
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+)
+
+func TestDescribePushType(t *testing.T) {
+	testCases := map[notification.PushType]string{
+		notification.Alert:        "alert: displays an alert, plays a sound, or badges the app's icon (topic: <bundle-id>)",
+		notification.Background:   "background: delivers content in the background without user interaction (topic: <bundle-id>)",
+		notification.Complication: "complication: updates a watchOS app's complication (topic: <bundle-id>.complication)",
+		notification.Controls:     "controls: updates the controls of a widget (topic: <bundle-id>.push-type.controls)",
+		notification.Fileprovider: "fileprovider: signals changes to a File Provider extension (topic: <bundle-id>.pushkit.fileprovider)",
+		notification.Liveactivity: "liveactivity: starts, updates, or ends a Live Activity (topic: <bundle-id>.push-type.liveactivity)",
+		notification.Location:     "location: requests the device's location through a Location Push Service Extension (topic: <bundle-id>.location-query)",
+		notification.Mdm:          "mdm: tells a device enrolled in MDM to contact its MDM server (topic: <bundle-id>)",
+		notification.Pushtotalk:   "pushtotalk: delivers a Push to Talk notification (topic: <bundle-id>.voip-ptt)",
+		notification.Voip:         "voip: delivers an incoming VoIP call through PushKit (topic: <bundle-id>.voip)",
+		notification.Widgets:      "widgets: reloads the content of a widget (topic: <bundle-id>.push-type.widgets)",
+		"unknown":                 `unknown push type "unknown"`,
+	}
+
+	for pushType, expected := range testCases {
+		t.Run(pushType, func(t *testing.T) {
+			if got := notification.DescribePushType(pushType); got != expected {
+				t.Errorf("DescribePushType(%q) = %q, want %q", pushType, got, expected)
+			}
+		})
+	}
+}
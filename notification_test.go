@@ -1,10 +1,15 @@
 package apns_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/uuid"
 	"github.com/takimoto3/apns"
 	"github.com/takimoto3/apns/notification"
@@ -39,6 +44,29 @@ func TestNotification_Topic(t *testing.T) {
 		{"Widgets", notification.Widgets, "com.example.myapp.push-type.widgets"},
 	}
 
+	// The suffix is appended even to a BundleID that already includes it, which
+	// ValidateStrict reports.
+	suffixed := []struct {
+		name     string
+		bundleID string
+		pushType notification.PushType
+		want     string
+	}{
+		{"Voip", "com.example.myapp.voip", notification.Voip, "com.example.myapp.voip.voip"},
+		{"Liveactivity", "com.example.myapp.push-type.liveactivity", notification.Liveactivity, "com.example.myapp.push-type.liveactivity.push-type.liveactivity"},
+		{"Complication", "com.example.myapp.complication", notification.Complication, "com.example.myapp.complication.complication"},
+		{"Other suffix", "com.example.myapp.voip", notification.Complication, "com.example.myapp.voip.complication"},
+		{"Alert", "com.example.myapp.voip", notification.Alert, "com.example.myapp.voip"},
+	}
+	for _, tt := range suffixed {
+		t.Run("Suffixed_"+tt.name, func(t *testing.T) {
+			n := apns.Notification{BundleID: tt.bundleID, Type: tt.pushType}
+			if got := n.Topic(); got != tt.want {
+				t.Errorf("Topic() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create the Notification struct instance
@@ -59,6 +87,43 @@ func TestNotification_Topic(t *testing.T) {
 	}
 }
 
+func TestNotification_CollapseKey(t *testing.T) {
+	base := apns.Notification{BundleID: "com.example.myapp", Type: notification.Alert, CollapseID: "score"}
+
+	if got, want := base.CollapseKey(), "com.example.myapp/score"; got != want {
+		t.Errorf("CollapseKey() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name         string
+		modify       func(n *apns.Notification)
+		wantCollapse bool
+	}{
+		{"Same collapse ID and topic", func(n *apns.Notification) { n.DeviceToken = "other" }, true},
+		{"Different collapse ID", func(n *apns.Notification) { n.CollapseID = "news" }, false},
+		{"Different bundle ID", func(n *apns.Notification) { n.BundleID = "com.example.other" }, false},
+		{"Different topic suffix", func(n *apns.Notification) { n.Type = notification.Widgets }, false},
+		{"Same topic for background", func(n *apns.Notification) { n.Type = notification.Background }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.modify(&other)
+			if got := other.CollapseKey() == base.CollapseKey(); got != tt.wantCollapse {
+				t.Errorf("CollapseKey() %q vs %q: collapse = %v, want %v", other.CollapseKey(), base.CollapseKey(), got, tt.wantCollapse)
+			}
+		})
+	}
+
+	t.Run("No collapse ID", func(t *testing.T) {
+		n := base
+		n.CollapseID = ""
+		if got := n.CollapseKey(); got != "" {
+			t.Errorf("CollapseKey() = %q, want empty", got)
+		}
+	})
+}
+
 func TestNotification_Validate(t *testing.T) {
 	validPayload := &apns.Payload{
 		APS: payload.APS{
@@ -136,6 +201,35 @@ func TestNotification_Validate(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		"Valid ChannelID": {
+			notification: &apns.Notification{
+				BundleID:  "com.example.app",
+				ChannelID: "dHN0LXNyY2gtY2hubA==",
+				Type:      notification.Liveactivity,
+				Payload:   &apns.Payload{APS: payload.APS{Event: "end", ContentState: map[string]any{"score": 3}}},
+			},
+			expectErr: false,
+		},
+		"ChannelID with DeviceToken": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				ChannelID:   "dHN0LXNyY2gtY2hubA==",
+				Type:        notification.Liveactivity,
+			},
+			expectErr:   true,
+			errContains: "mutually exclusive",
+		},
+		"ChannelID with alert push type": {
+			notification: &apns.Notification{
+				BundleID:  "com.example.app",
+				ChannelID: "dHN0LXNyY2gtY2hubA==",
+				Type:      notification.Alert,
+				Payload:   validPayload,
+			},
+			expectErr:   true,
+			errContains: "ChannelID requires the liveactivity push type",
+		},
 		"Invalid Priority": {
 			notification: &apns.Notification{
 				BundleID:    "com.example.app",
@@ -194,6 +288,27 @@ func TestNotification_Validate(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		"Background with Priority Immediate": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Background,
+				Priority:    priority.Immediate,
+				Payload:     &apns.Payload{APS: payload.APS{ContentAvailable: 1}},
+			},
+			expectErr:   true,
+			errContains: "apns-priority 10 is not allowed for the background push type",
+		},
+		"Background with Priority Conserve": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Background,
+				Priority:    priority.Conserve,
+				Payload:     &apns.Payload{APS: payload.APS{ContentAvailable: 1}},
+			},
+			expectErr: false,
+		},
 		"Missing Payload for Background": {
 			notification: &apns.Notification{
 				BundleID:    "com.example.app",
@@ -215,6 +330,70 @@ func TestNotification_Validate(t *testing.T) {
 			expectErr:   true,
 			errContains: "aps dictionary must not be empty",
 		},
+		"CollapseID of 64 bytes": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     validPayload,
+				CollapseID:  strings.Repeat("a", 64),
+			},
+			expectErr: false,
+		},
+		"CollapseID of 65 bytes": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     validPayload,
+				CollapseID:  strings.Repeat("a", 65),
+			},
+			expectErr:   true,
+			errContains: "CollapseID must not exceed 64 bytes",
+		},
+		"CollapseID of 22 runes over 64 bytes": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     validPayload,
+				CollapseID:  strings.Repeat("あ", 22),
+			},
+			expectErr:   true,
+			errContains: "CollapseID must not exceed 64 bytes",
+		},
+		"Custom header": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     validPayload,
+				Headers:     map[string]string{"X-Request-ID": "abc"},
+			},
+			expectErr: false,
+		},
+		"Reserved apns header": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     validPayload,
+				Headers:     map[string]string{"APNS-Topic": "com.other.app"},
+			},
+			expectErr:   true,
+			errContains: `header "APNS-Topic" is reserved`,
+		},
+		"Reserved authorization header": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     validPayload,
+				Headers:     map[string]string{"authorization": "bearer x"},
+			},
+			expectErr:   true,
+			errContains: `header "authorization" is reserved`,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -234,3 +413,466 @@ func TestNotification_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestNotification_Validate_LiveActivity(t *testing.T) {
+	testCases := map[string]struct {
+		aps       payload.APS
+		wantField string
+		wantCode  string
+	}{
+		"update without content-state": {
+			aps:       payload.APS{Event: "update", Timestamp: notification.NewEpochTime(time.Now())},
+			wantField: "content-state",
+			wantCode:  payload.CodeRequiredForUpdate,
+		},
+		"invalid event": {
+			aps:       payload.APS{Event: "pause", ContentState: map[string]any{"status": "running"}},
+			wantField: "event",
+			wantCode:  payload.CodeInvalidValue,
+		},
+		"valid update": {
+			aps: payload.APS{Event: "update", ContentState: map[string]any{"status": "running"}},
+		},
+		"missing event": {
+			aps:       payload.APS{ContentState: map[string]any{"status": "running"}},
+			wantField: "event",
+			wantCode:  payload.CodeRequired,
+		},
+		"valid start": {
+			aps: payload.APS{
+				Event:          "start",
+				AttributesType: "DeliveryAttributes",
+				Attributes:     map[string]any{"order": "1234"},
+				ContentState:   map[string]any{"status": "preparing"},
+			},
+		},
+		"start without attributes-type": {
+			aps:       payload.APS{Event: "start", Attributes: map[string]any{"order": "1234"}},
+			wantField: "attributes-type",
+			wantCode:  payload.CodeRequiredForStart,
+		},
+		"start without attributes": {
+			aps:       payload.APS{Event: "start", AttributesType: "DeliveryAttributes"},
+			wantField: "attributes",
+			wantCode:  payload.CodeRequiredForStart,
+		},
+		"valid end": {
+			aps: payload.APS{Event: "end", ContentState: map[string]any{"status": "delivered"}},
+		},
+		"end without content-state": {
+			aps:       payload.APS{Event: "end"},
+			wantField: "content-state",
+			wantCode:  payload.CodeRequiredForEnd,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Liveactivity,
+				Payload:     &apns.Payload{APS: tc.aps},
+			}
+			err := n.Validate()
+			if tc.wantCode == "" {
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *payload.ValidationError, got %v (%T)", err, err)
+			}
+			if verr.Field != tc.wantField {
+				t.Errorf("Field = %q, want %q", verr.Field, tc.wantField)
+			}
+			if verr.Code != tc.wantCode {
+				t.Errorf("Code = %q, want %q", verr.Code, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestNotification_Validate_Background(t *testing.T) {
+	testCases := map[string]struct {
+		aps       payload.APS
+		wantField string // If non-empty, a background_user_content error on this field is expected
+	}{
+		"clean background push": {
+			aps: payload.APS{ContentAvailable: 1},
+		},
+		"background push with custom category": {
+			aps: payload.APS{ContentAvailable: 1, Category: "sync"},
+		},
+		"background push with alert": {
+			aps:       payload.APS{ContentAvailable: 1, Alert: "hello"},
+			wantField: "alert",
+		},
+		"background push with sound": {
+			aps:       payload.APS{ContentAvailable: 1, Sound: "default"},
+			wantField: "sound",
+		},
+		"background push with badge": {
+			aps:       payload.APS{ContentAvailable: 1, Badge: 3},
+			wantField: "badge",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Background,
+				Priority:    priority.Conserve,
+				Payload:     &apns.Payload{APS: tc.aps},
+			}
+			err := n.Validate()
+			if tc.wantField == "" {
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *payload.ValidationError, got %v (%T)", err, err)
+			}
+			if verr.Field != tc.wantField || verr.Code != payload.CodeBackgroundUserContent {
+				t.Errorf("ValidationError = {Field:%q Code:%q}, want {Field:%q Code:%q}", verr.Field, verr.Code, tc.wantField, payload.CodeBackgroundUserContent)
+			}
+			if !strings.Contains(err.Error(), "background push type must not include "+tc.wantField) {
+				t.Errorf("unexpected error message: %s", err)
+			}
+
+			// The same payload is fine under the alert push type.
+			n.Type = notification.Alert
+			if err := n.Validate(); err != nil {
+				t.Errorf("did not expect an error for the alert push type, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNotification_Validate_VoIP(t *testing.T) {
+	testCases := map[string]struct {
+		bundleID string
+		payload  *apns.Payload
+		wantCode string // If non-empty, a validation error with this code is expected
+	}{
+		"valid voip push": {
+			bundleID: "com.example.app",
+			payload:  &apns.Payload{APS: payload.APS{Alert: "call"}, CustomData: map[string]any{"caller": "Alice", "uuid": "abc"}},
+		},
+		"voip push without payload": {
+			bundleID: "com.example.app",
+		},
+		"bundle id with voip suffix": {
+			bundleID: "com.example.app.voip",
+			payload:  &apns.Payload{APS: payload.APS{Alert: "call"}},
+		},
+		"background-like voip payload": {
+			bundleID: "com.example.app",
+			payload:  &apns.Payload{APS: payload.APS{ContentAvailable: 1}, CustomData: map[string]any{"sync": "full"}},
+			wantCode: payload.CodeVoIPContentAvailable,
+		},
+		"bundle id with push to talk suffix": {
+			bundleID: "com.example.app.voip-ptt",
+			payload:  &apns.Payload{APS: payload.APS{Alert: "call"}},
+			wantCode: payload.CodeTopicSuffixMismatch,
+		},
+		"bundle id with complication suffix": {
+			bundleID: "com.example.app.complication",
+			wantCode: payload.CodeTopicSuffixMismatch,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := &apns.Notification{
+				BundleID:    tc.bundleID,
+				DeviceToken: "some-device-token",
+				Type:        notification.Voip,
+				Payload:     tc.payload,
+			}
+			err := n.Validate()
+			if tc.wantCode == "" {
+				if err != nil {
+					t.Fatalf("did not expect an error, but got: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *payload.ValidationError, got %v (%T)", err, err)
+			}
+			if verr.Code != tc.wantCode {
+				t.Errorf("ValidationError.Code = %q, want %q", verr.Code, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestNotification_Validate_ReservedCustomKey(t *testing.T) {
+	n := &apns.Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "some-device-token",
+		Type:        notification.Alert,
+		Payload: &apns.Payload{
+			APS:        payload.APS{Alert: "hello"},
+			CustomData: map[string]any{"aps": map[string]any{"badge": 1}},
+		},
+	}
+
+	err := n.Validate()
+	if !errors.Is(err, apns.ErrReservedCustomKey) {
+		t.Fatalf("Validate() error = %v, want %v", err, apns.ErrReservedCustomKey)
+	}
+
+	// Without validation, the fast encoder would emit "aps" twice at the root.
+	body, err := n.Payload.MarshalJSONFast()
+	if err != nil {
+		t.Fatalf("MarshalJSONFast failed: %v", err)
+	}
+	if got := countRootKeys(t, body, "aps"); got != 2 {
+		t.Errorf("expected the duplicate \"aps\" key to be encoded twice, got %d in %s", got, body)
+	}
+}
+
+// countRootKeys returns how many times key occurs in the top-level object of data.
+func countRootKeys(t *testing.T, data []byte, key string) int {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("expected a top-level object, got %v (%v)", tok, err)
+	}
+	count := 0
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatalf("failed to read key: %v", err)
+		}
+		if tok == key {
+			count++
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatalf("failed to read value: %v", err)
+		}
+	}
+	return count
+}
+
+func TestNotification_Clone(t *testing.T) {
+	exp := notification.NewEpochTime(time.Now().Add(time.Hour))
+	original := &apns.Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "token",
+		Type:        notification.Liveactivity,
+		Expiration:  exp,
+		Headers:     map[string]string{"X-Request-ID": "1"},
+		Payload: &apns.Payload{
+			APS: payload.APS{
+				Alert:        &payload.Alert{Title: "title", LocArgs: []string{"a"}},
+				Event:        "update",
+				ContentState: map[string]any{"score": 1, "nested": map[string]any{"k": "v"}, "list": []any{1, 2}},
+				Attributes:   map[string]any{"team": "home"},
+			},
+			CustomData: map[string]any{"id": 1, "tags": []string{"x"}},
+		},
+	}
+	want := &apns.Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "token",
+		Type:        notification.Liveactivity,
+		Expiration:  func() *notification.EpochTime { e := *exp; return &e }(),
+		Headers:     map[string]string{"X-Request-ID": "1"},
+		Payload: &apns.Payload{
+			APS: payload.APS{
+				Alert:        &payload.Alert{Title: "title", LocArgs: []string{"a"}},
+				Event:        "update",
+				ContentState: map[string]any{"score": 1, "nested": map[string]any{"k": "v"}, "list": []any{1, 2}},
+				Attributes:   map[string]any{"team": "home"},
+			},
+			CustomData: map[string]any{"id": 1, "tags": []string{"x"}},
+		},
+	}
+
+	clone := original.Clone()
+	if diff := cmp.Diff(original, clone, cmpopts.IgnoreUnexported(apns.Notification{})); diff != "" {
+		t.Fatalf("clone differs from original (-original +clone):\n%s", diff)
+	}
+
+	// Mutate the clone's maps while the original is read concurrently;
+	// run with -race to detect shared state.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if _, err := original.Payload.MarshalJSON(); err != nil {
+				t.Errorf("MarshalJSON failed: %v", err)
+				return
+			}
+			_ = original.Headers["X-Request-ID"]
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		clone.Headers["X-Request-ID"] = "2"
+		clone.Payload.APS.ContentState["score"] = i
+		clone.Payload.APS.ContentState["nested"].(map[string]any)["k"] = i
+		clone.Payload.APS.ContentState["list"].([]any)[0] = i
+		clone.Payload.APS.Attributes["team"] = "away"
+		clone.Payload.APS.Alert.(*payload.Alert).LocArgs[0] = "b"
+		clone.Payload.CustomData["id"] = i
+		clone.Payload.CustomData["tags"].([]string)[0] = "y"
+		*clone.Expiration = 0
+	}
+	<-done
+
+	if diff := cmp.Diff(want, original, cmpopts.IgnoreUnexported(apns.Notification{})); diff != "" {
+		t.Errorf("original was modified through the clone (-want +got):\n%s", diff)
+	}
+}
+
+func TestNotification_ValidateStrict(t *testing.T) {
+	testCases := map[string]struct {
+		notification *apns.Notification
+		wantCode     string // If non-empty, a *payload.ValidationError with this code is expected
+	}{
+		"content-available under background": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Background,
+				Priority:    priority.Conserve,
+				Payload:     &apns.Payload{APS: payload.APS{ContentAvailable: 1}},
+			},
+		},
+		"content-available under alert": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     &apns.Payload{APS: payload.APS{ContentAvailable: 1}},
+			},
+			wantCode: payload.CodeBackgroundPushType,
+		},
+		"content-available with alert under alert": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "hello", ContentAvailable: 1}},
+			},
+			wantCode: payload.CodeContentAvailableWithAlert,
+		},
+		"bundle ID with the suffix of its push type": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app.voip",
+				DeviceToken: "some-device-token",
+				Type:        notification.Voip,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "Incoming call"}},
+			},
+			wantCode: payload.CodeDoubledTopicSuffix,
+		},
+		"bundle ID with the suffix of another push type": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app.voip",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "hello"}},
+			},
+		},
+		"lowercase bundle ID": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.my-app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "hello"}},
+			},
+		},
+		"uppercase bundle ID": {
+			notification: &apns.Notification{
+				BundleID:    "com.Example.MyApp",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "hello"}},
+			},
+			wantCode: payload.CodeUppercaseBundleID,
+		},
+		"critical mismatch in aps": {
+			notification: &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "hello", InterruptionLevel: "critical"}},
+			},
+			wantCode: payload.CodeCriticalMismatch,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := tc.notification.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			err := tc.notification.ValidateStrict()
+			if tc.wantCode == "" {
+				if err != nil {
+					t.Errorf("ValidateStrict() unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) || verr.Code != tc.wantCode {
+				t.Errorf("ValidateStrict() error = %v, want *payload.ValidationError with code %q", err, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestNotification_ValidateExpiration(t *testing.T) {
+	now := time.Now()
+	testCases := map[string]struct {
+		expiration *notification.EpochTime
+		wantErr    bool
+	}{
+		"nil":        {expiration: nil},
+		"once":       {expiration: notification.ExpirationOnce},
+		"one day":    {expiration: notification.NewEpochTime(now.Add(24 * time.Hour))},
+		"29 days":    {expiration: notification.NewEpochTime(now.Add(29 * 24 * time.Hour))},
+		"1000 years": {expiration: notification.NewEpochTime(now.AddDate(1000, 0, 0)), wantErr: true},
+		"milliseconds as seconds": {
+			expiration: func() *notification.EpochTime { e := notification.EpochTime(now.Add(time.Hour).UnixMilli()); return &e }(),
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := &apns.Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "some-device-token",
+				Type:        notification.Alert,
+				Expiration:  tc.expiration,
+				Payload:     &apns.Payload{APS: payload.APS{Alert: "hello"}},
+			}
+			if err := n.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			err := n.ValidateStrict()
+			if !tc.wantErr {
+				if err != nil {
+					t.Errorf("ValidateStrict() unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) || verr.Code != payload.CodeExpirationTooFar {
+				t.Errorf("ValidateStrict() error = %v, want code %q", err, payload.CodeExpirationTooFar)
+			}
+		})
+	}
+}
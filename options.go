@@ -0,0 +1,260 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/takimoto3/appleapi-core"
+)
+
+// WithTimeout returns an option that sets the timeout of the client's HTTP client,
+// covering the whole exchange of each request including reading the response.
+// It can be passed to NewClient, NewClientWithToken and NewClientWithCert along
+// with other appleapi options. A zero duration means no timeout.
+func WithTimeout(d time.Duration) appleapi.Option {
+	return appleapi.WithClientTimeout(d)
+}
+
+// Option configures a Client after it has been created. Unlike appleapi options,
+// which are applied by the constructors, Options are applied with Client.Configure
+// and may fail. They must be applied before the client is first used.
+type Option func(*Client) error
+
+// ErrClientInUse is returned by Configure once the client has been used.
+var ErrClientInUse = errors.New("apns: options cannot be applied to a client in use")
+
+// Configure applies the options to the client in order and returns the first
+// error encountered. Once any method sending requests or preparing bodies, such
+// as Push, has been called, the client is in use and Configure fails with
+// ErrClientInUse, so that the settings applied by options never change while
+// they are being read. It is safe to call Configure concurrently with such methods.
+func (cli *Client) Configure(opts ...Option) error {
+	cli.configMu.Lock()
+	defer cli.configMu.Unlock()
+	if cli.frozen.Load() {
+		return ErrClientInUse
+	}
+	for _, opt := range opts {
+		if err := opt(cli); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freeze marks the client as in use, after which Configure fails. Methods that
+// read the settings applied by options call it first; holding configMu makes
+// a Configure in progress complete before the settings are read.
+func (cli *Client) freeze() {
+	if cli.frozen.Load() {
+		return
+	}
+	cli.configMu.Lock()
+	cli.frozen.Store(true)
+	cli.configMu.Unlock()
+}
+
+// WithTokenLimits returns an option that sets the client's TokenLimits, the
+// maximum number of device tokens of a batch. n must be positive.
+func WithTokenLimits(n int) Option {
+	return func(cli *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("token limit must be positive, got %d", n)
+		}
+		cli.TokenLimits = n
+		return nil
+	}
+}
+
+// WithFastJSON returns an option that sets the client's FastJson, selecting the
+// custom JSON encoder if enabled is true and `encoding/json` otherwise.
+func WithFastJSON(enabled bool) Option {
+	return func(cli *Client) error {
+		cli.FastJson = enabled
+		return nil
+	}
+}
+
+// WithMinTLSVersion returns an option that sets the minimum TLS version used
+// to connect to APNs. The constructors require TLS 1.3; use this option to
+// allow TLS 1.2, e.g. for TLS-inspecting proxies that do not support TLS 1.3.
+// Only tls.VersionTLS12 and tls.VersionTLS13 are accepted.
+func WithMinTLSVersion(v uint16) Option {
+	return func(cli *Client) error {
+		if v != tls.VersionTLS12 && v != tls.VersionTLS13 {
+			return fmt.Errorf("unsupported minimum TLS version: %#04x (must be TLS 1.2 or TLS 1.3)", v)
+		}
+		tr, ok := cli.inner.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot set the minimum TLS version on transport of type %T", cli.inner.HTTPClient.Transport)
+		}
+		config := &tls.Config{}
+		if tr.TLSClientConfig != nil {
+			config = tr.TLSClientConfig.Clone()
+		}
+		config.MinVersion = v
+		tr.TLSClientConfig = config
+		return nil
+	}
+}
+
+// WithRootCAs returns an option that makes the client verify the certificate
+// of the server against pool instead of the system roots, e.g. to reach a mock
+// server or a TLS-inspecting proxy with a private certificate authority.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(cli *Client) error {
+		if pool == nil {
+			return errors.New("root certificate pool cannot be nil")
+		}
+		tr, ok := cli.inner.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot set the root certificates on transport of type %T", cli.inner.HTTPClient.Transport)
+		}
+		config := &tls.Config{}
+		if tr.TLSClientConfig != nil {
+			config = tr.TLSClientConfig.Clone()
+		}
+		config.RootCAs = pool
+		tr.TLSClientConfig = config
+		return nil
+	}
+}
+
+// WithKeepAlive returns an option that makes the client send an HTTP/2 PING
+// frame on a connection that has received no frames for readIdle, and close the
+// connection if the PING is not answered within pingTimeout. This keeps idle
+// connections to APNs warm and detects connections silently dropped by
+// intermediaries before a push is sent on them. A zero pingTimeout uses the
+// HTTP/2 default of 15 seconds.
+func WithKeepAlive(readIdle, pingTimeout time.Duration) Option {
+	return func(cli *Client) error {
+		if readIdle <= 0 {
+			return fmt.Errorf("keep-alive read idle timeout must be positive, got %v", readIdle)
+		}
+		if pingTimeout < 0 {
+			return fmt.Errorf("keep-alive ping timeout must not be negative, got %v", pingTimeout)
+		}
+		tr, ok := cli.inner.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot configure keep-alive on transport of type %T", cli.inner.HTTPClient.Transport)
+		}
+		config := &http.HTTP2Config{}
+		if tr.HTTP2 != nil {
+			*config = *tr.HTTP2
+		}
+		config.SendPingTimeout = readIdle
+		config.PingTimeout = pingTimeout
+		tr.HTTP2 = config
+		return nil
+	}
+}
+
+// WithHost returns an option that sends notifications to host, a base URL such
+// as "https://localhost:8443", in place of ProductionHost or DevelopmentHost,
+// e.g. to go through a local proxy or to reach a mock server. Since it replaces
+// the host of either environment, it takes precedence over appleapi.WithDevelopment
+// once the client has been created. A trailing slash is ignored.
+func WithHost(host string) Option {
+	return func(cli *Client) error {
+		u, err := url.Parse(host)
+		if err != nil {
+			return fmt.Errorf("invalid host %q: %w", host, err)
+		}
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid host %q: must be an absolute http or https URL", host)
+		}
+		cli.inner.Host = strings.TrimSuffix(host, "/")
+		return nil
+	}
+}
+
+// AlternatePort is the port APNs also listens on, for environments where
+// outbound connections to port 443 are blocked or throttled.
+const AlternatePort = "2197"
+
+// WithPort2197 returns an option that connects to APNs on AlternatePort
+// instead of 443, in both the production and the development environment.
+// It must be applied after the environment has been selected.
+func WithPort2197() Option {
+	return func(cli *Client) error {
+		u, err := url.Parse(cli.inner.Host)
+		if err != nil {
+			return fmt.Errorf("invalid host %q: %w", cli.inner.Host, err)
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), AlternatePort)
+		cli.inner.Host = u.String()
+		return nil
+	}
+}
+
+// WithMaxConnsPerHost returns an option that limits the number of connections
+// to APNs, idle or in use, to n. Since HTTP/2 multiplexes requests over each
+// connection, a few connections are usually enough; raise it for massive
+// fan-out. Zero means no limit.
+func WithMaxConnsPerHost(n int) Option {
+	return func(cli *Client) error {
+		if n < 0 {
+			return fmt.Errorf("max connections per host must not be negative, got %d", n)
+		}
+		tr, err := cli.httpTransport("max connections")
+		if err != nil {
+			return err
+		}
+		tr.MaxConnsPerHost = n
+		return nil
+	}
+}
+
+// WithMaxIdleConns returns an option that keeps up to n idle connections to
+// APNs open for reuse. Zero means the default of net/http.
+func WithMaxIdleConns(n int) Option {
+	return func(cli *Client) error {
+		if n < 0 {
+			return fmt.Errorf("max idle connections must not be negative, got %d", n)
+		}
+		tr, err := cli.httpTransport("max idle connections")
+		if err != nil {
+			return err
+		}
+		tr.MaxIdleConnsPerHost = n
+		// MaxIdleConns caps the idle connections across all hosts.
+		if tr.MaxIdleConns != 0 && tr.MaxIdleConns < n {
+			tr.MaxIdleConns = n
+		}
+		return nil
+	}
+}
+
+// WithIdleConnTimeout returns an option that closes connections to APNs that
+// have been idle for d. Zero means no limit.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(cli *Client) error {
+		if d < 0 {
+			return fmt.Errorf("idle connection timeout must not be negative, got %v", d)
+		}
+		tr, err := cli.httpTransport("idle connection timeout")
+		if err != nil {
+			return err
+		}
+		tr.IdleConnTimeout = d
+		return nil
+	}
+}
+
+// httpTransport returns the transport of the client's HTTP client, or an error
+// naming the setting if it is not a standard *http.Transport.
+func (cli *Client) httpTransport(setting string) (*http.Transport, error) {
+	tr, ok := cli.inner.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("cannot configure %s on transport of type %T", setting, cli.inner.HTTPClient.Transport)
+	}
+	return tr, nil
+}
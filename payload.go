@@ -3,6 +3,8 @@ package apns
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"maps"
 
 	"github.com/takimoto3/apns/payload"
@@ -19,10 +21,73 @@ type Payload struct {
 
 	// CustomData is a map for any app-specific custom data.
 	// The keys and values in this map will be merged at the root level of the
-	// JSON payload, alongside the `aps` dictionary.
+	// JSON payload, alongside the `aps` dictionary, so the key "aps" is reserved
+	// and rejected by Notification.Validate.
 	CustomData map[string]any `json:",inline"`
 }
 
+// ErrReservedCustomKey is returned when custom data is set with the key "aps",
+// which is reserved for the APS dictionary.
+var ErrReservedCustomKey = errors.New(`custom data key "aps" is reserved`)
+
+// SetCustom sets the custom data value for the key, initializing CustomData if
+// it is nil. It returns ErrReservedCustomKey if the key is "aps".
+func (p *Payload) SetCustom(key string, value any) error {
+	if key == "aps" {
+		return ErrReservedCustomKey
+	}
+	if p.CustomData == nil {
+		p.CustomData = make(map[string]any)
+	}
+	p.CustomData[key] = value
+	return nil
+}
+
+// MergeCustom copies the entries of m into CustomData, initializing it if it is
+// nil. Values in m replace existing values with the same key; the values are not
+// copied deeply. If m contains the key "aps", it returns ErrReservedCustomKey
+// and CustomData is left unchanged.
+func (p *Payload) MergeCustom(m map[string]any) error {
+	if _, ok := m["aps"]; ok {
+		return ErrReservedCustomKey
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	if p.CustomData == nil {
+		p.CustomData = make(map[string]any, len(m))
+	}
+	maps.Copy(p.CustomData, m)
+	return nil
+}
+
+// Clone returns a deep copy of the payload, including the maps of the APS
+// dictionary and CustomData.
+func (p *Payload) Clone() *Payload {
+	return &Payload{
+		APS:        p.APS.Clone(),
+		CustomData: payload.CloneMap(p.CustomData),
+	}
+}
+
+// PayloadMarshaler encodes a Payload into the JSON body of an APNs request.
+// It can be set on Client.Marshaler to plug in a third-party encoder or to add
+// instrumentation around encoding.
+type PayloadMarshaler interface {
+	Marshal(p *Payload) ([]byte, error)
+}
+
+// Marshal encodes the payload into the JSON body of an APNs request, independent
+// of any Client. If fast is true, the custom encoder of MarshalJSONFast is used;
+// otherwise the payload is encoded with `encoding/json`. Both produce equivalent JSON,
+// but the fast encoder fails with payload.ErrInvalidType on unsupported CustomData types.
+func (p *Payload) Marshal(fast bool) ([]byte, error) {
+	if fast {
+		return p.MarshalJSONFast()
+	}
+	return json.Marshal(p)
+}
+
 // MarshalJSON implements the `json.Marshaler` interface.
 // It customizes the JSON output by merging the `APS` dictionary and the `CustomData`
 // map at the root level of the payload. This is necessary because the `json:",inline"`
@@ -38,3 +103,39 @@ func (p *Payload) MarshalJSON() ([]byte, error) {
 	mp["aps"] = p.APS
 	return json.Marshal(mp)
 }
+
+// MarshalIndent encodes the payload like MarshalJSON, but indented with two
+// spaces for logging and debugging. It is not meant for the wire: APNs counts
+// the whitespace against the payload size limit.
+func (p *Payload) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// UnmarshalJSON implements the `json.Unmarshaler` interface, reversing MarshalJSON.
+// The `aps` dictionary is decoded into APS and every other root-level key into
+// CustomData, which is left nil if there are none. Custom values are decoded
+// with payload.DecodeValue, so integers become int and objects map[string]any.
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*p = Payload{}
+	for key, raw := range fields {
+		if key == "aps" {
+			if err := json.Unmarshal(raw, &p.APS); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := payload.DecodeValue(raw)
+		if err != nil {
+			return fmt.Errorf("invalid custom data %q: %w", key, err)
+		}
+		if p.CustomData == nil {
+			p.CustomData = make(map[string]any, len(fields))
+		}
+		p.CustomData[key] = value
+	}
+	return nil
+}
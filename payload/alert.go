@@ -1,9 +1,15 @@
 // package payload provides types for constructing the payload of an APNs notification.
 package payload
 
+import "fmt"
+
 // Alert represents the `alert` dictionary within the `aps` payload.
 // It defines the content and appearance of the user-facing notification.
 //
+// When both a literal string and its localization key are set (e.g. Body and
+// LocKey), the device displays the localized string if the key is found in the
+// app's `Localizable.strings` and falls back to the literal string otherwise.
+//
 // For more details, see the Apple Developer Documentation:
 // https://developer.apple.com/documentation/usernotifications/generating_a_remote_notification
 type Alert struct {
@@ -47,4 +53,41 @@ type Alert struct {
 
 	// SubtitleLocArgs are the arguments for `subtitle-loc-key`.
 	SubtitleLocArgs []string `json:"subtitle-loc-args,omitempty"`
+
+	// --- Notification grouping ---
+
+	// SummaryArg is the string the system adds to the category's summary format
+	// string when notifications are grouped, e.g. the name of a conversation.
+	SummaryArg string `json:"summary-arg,omitempty"`
+
+	// SummaryArgCount is the number of items the notification adds to the
+	// category's summary format string, e.g. 3 for a notification about three
+	// new messages. If it is omitted, the system counts the notification as one.
+	SummaryArgCount int `json:"summary-arg-count,omitempty"`
+}
+
+// Validate checks that localization arguments are accompanied by their keys.
+// Arguments without their key are reported as a *ValidationError with the code
+// CodeLocArgsWithoutKey.
+func (a *Alert) Validate() error {
+	if len(a.LocArgs) > 0 && a.LocKey == "" {
+		return locArgsWithoutKey("loc-args", "loc-key")
+	}
+	if len(a.TitleLocArgs) > 0 && a.TitleLocKey == "" {
+		return locArgsWithoutKey("title-loc-args", "title-loc-key")
+	}
+	if len(a.SubtitleLocArgs) > 0 && a.SubtitleLocKey == "" {
+		return locArgsWithoutKey("subtitle-loc-args", "subtitle-loc-key")
+	}
+	return nil
+}
+
+// locArgsWithoutKey reports localization arguments set without their key,
+// which APNs ignores.
+func locArgsWithoutKey(args, key string) error {
+	return &ValidationError{
+		Field:   key,
+		Code:    CodeLocArgsWithoutKey,
+		Message: fmt.Sprintf("alert %s requires %s: the arguments are ignored without it", args, key),
+	}
 }
@@ -5,7 +5,10 @@
 package payload
 
 import (
+	"strconv"
 	"sync"
+
+	"github.com/takimoto3/apns/internal/bufstats"
 )
 
 const hex = "0123456789abcdef"
@@ -25,6 +28,7 @@ func (a Alert) MarshalJSONFast() ([]byte, error) {
 	ptr := alertPool.Get().(*[]byte)
 	b := (*ptr)[:0]
 	defer func() {
+		bufstats.Observe(len(b))
 		*ptr = b
 		alertPool.Put(ptr)
 	}()
@@ -121,7 +125,20 @@ func (a Alert) MarshalJSONFast() ([]byte, error) {
 		addComma()
 		addString("action-loc-key", a.ActionLocKey)
 	}
+
+	if a.SummaryArg != "" {
+		addComma()
+		addString("summary-arg", a.SummaryArg)
+	}
+	if a.SummaryArgCount != 0 {
+		addComma()
+		b = append(b, `"summary-arg-count":`...)
+		b = strconv.AppendInt(b, int64(a.SummaryArgCount), 10)
+	}
 	b = append(b, '}')
 
-	return b, nil
+	// b is returned to the pool on exit, so hand out a copy.
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
 }
@@ -27,6 +27,8 @@ func TestAlertMarshalJSONTo3(t *testing.T) {
 				SubtitleLocKey:  "GAME_SUB_KEY",
 				SubtitleLocArgs: []string{"Bob"},
 				ActionLocKey:    "PLAY",
+				SummaryArg:      "Bob",
+				SummaryArgCount: 2,
 			},
 			want: `{
 				"title":"Game Request",
@@ -39,10 +41,37 @@ func TestAlertMarshalJSONTo3(t *testing.T) {
 				"title-loc-args":["Bob"],
 				"subtitle-loc-key":"GAME_SUB_KEY",
 				"subtitle-loc-args":["Bob"],
-				"action-loc-key":"PLAY"
+				"action-loc-key":"PLAY",
+				"summary-arg":"Bob",
+				"summary-arg-count":2
 			}`,
 		},
 
+		"summary arg only": {
+			input: payload.Alert{
+				Body:       "New message",
+				SummaryArg: "Family chat",
+			},
+			want: `{"body":"New message","summary-arg":"Family chat"}`,
+		},
+
+		"summary arg count": {
+			input: payload.Alert{
+				Body:            "3 new messages",
+				SummaryArg:      "Family chat",
+				SummaryArgCount: 3,
+			},
+			want: `{"body":"3 new messages","summary-arg":"Family chat","summary-arg-count":3}`,
+		},
+
+		"zero summary arg count omitted": {
+			input: payload.Alert{
+				Body:            "hi",
+				SummaryArgCount: 0,
+			},
+			want: `{"body":"hi"}`,
+		},
+
 		"only title": {
 			input: payload.Alert{
 				Title: "Hello",
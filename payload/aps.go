@@ -7,6 +7,7 @@ import (
 
 	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/payload/interruptionlevel"
+	"github.com/takimoto3/apns/payload/sound"
 )
 
 // APS represents the `aps` dictionary, which is the core of an APNs payload.
@@ -18,6 +19,8 @@ import (
 type APS struct {
 	// Alert is the content of the alert message.
 	// It can be a simple string or a dictionary object (payload.Alert).
+	// A string is displayed as the notification body; use an Alert to set a
+	// title or localized text.
 	Alert any `json:"alert,omitempty"`
 
 	// Badge is the number to display in a badge on the app's icon.
@@ -64,6 +67,7 @@ type APS struct {
 	Timestamp *notification.EpochTime `json:"timestamp,omitempty"`
 
 	// TargetContentID is the identifier of the window that will be brought forward.
+	// It is a key of the `aps` dictionary, `target-content-id`, not of the alert.
 	TargetContentID string `json:"target-content-id,omitempty"`
 
 	// ContentState is the dictionary that contains the dynamic data for a Live Activity.
@@ -85,6 +89,71 @@ type APS struct {
 	Attributes map[string]any `json:"attributes,omitempty"`
 }
 
+// ValidateStrict performs the checks of Validate and, if they pass, additional
+// advisory checks for combinations that APNs accepts but that rarely behave as
+// intended. Advisory failures are reported as *ValidationError.
+//
+// The advisory rules are:
+//   - An InterruptionLevel of `critical` requires a Sound (or *Sound) with Critical
+//     set to sound.Critical; otherwise the alert is not played as a critical alert.
+//   - A critical Sound requires the InterruptionLevel to be `critical` or unset
+//     (the latter for systems that predate interruption levels).
+//   - ContentAvailable should not be combined with an Alert: the alert is shown,
+//     but the system does not reliably run the app's background handler.
+//   - Attributes and AttributesType are only used to start a Live Activity and
+//     should be omitted unless Event is `start`.
+func (aps *APS) ValidateStrict() error {
+	if err := aps.Validate(); err != nil {
+		return err
+	}
+
+	var criticalSound bool
+	switch s := aps.Sound.(type) {
+	case Sound:
+		criticalSound = s.Critical == sound.Critical
+	case *Sound:
+		criticalSound = s != nil && s.Critical == sound.Critical
+	}
+	if aps.InterruptionLevel == interruptionlevel.Critical && !criticalSound {
+		return &ValidationError{
+			Field:   "sound",
+			Code:    CodeCriticalMismatch,
+			Message: "critical interruption-level requires a sound with the critical flag set",
+		}
+	}
+	if criticalSound && aps.InterruptionLevel != "" && aps.InterruptionLevel != interruptionlevel.Critical {
+		return &ValidationError{
+			Field:   "interruption-level",
+			Code:    CodeCriticalMismatch,
+			Message: fmt.Sprintf("critical sound requires the critical interruption-level, got %s", aps.InterruptionLevel),
+		}
+	}
+	if aps.ContentAvailable != nil && aps.Alert != nil {
+		return &ValidationError{
+			Field:   "content-available",
+			Code:    CodeContentAvailableWithAlert,
+			Message: "content-available with an alert does not reliably wake the app in the background; send a separate background notification",
+		}
+	}
+	if aps.Event != "start" {
+		field := ""
+		switch {
+		case len(aps.Attributes) > 0:
+			field = "attributes"
+		case aps.AttributesType != "":
+			field = "attributes-type"
+		}
+		if field != "" {
+			return &ValidationError{
+				Field:   field,
+				Code:    CodeAttributesNotStart,
+				Message: fmt.Sprintf("%s is only used by the start event and should be omitted for event %q", field, aps.Event),
+			}
+		}
+	}
+	return nil
+}
+
 // Validate checks the types and values of the fields in the APS dictionary.
 // It ensures that fields like Alert, Badge, and Sound have compatible types,
 // and that values like RelevanceScore and InterruptionLevel are within valid ranges.
@@ -105,11 +174,23 @@ func (aps *APS) Validate() error {
 		return errors.New("aps dictionary must not be empty")
 	}
 
-	// Validate Alert
+	// Validate Alert. A string alert is displayed as the body of the notification;
+	// titles and localized text require an Alert dictionary.
 	if aps.Alert != nil {
-		switch aps.Alert.(type) {
-		case string, Alert, *Alert:
-			// valid types
+		switch a := aps.Alert.(type) {
+		case string:
+			// valid type
+		case Alert:
+			if err := a.Validate(); err != nil {
+				return err
+			}
+		case *Alert:
+			if a == nil {
+				return errors.New("aps.Alert must not be a nil *Alert")
+			}
+			if err := a.Validate(); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("invalid type for aps.Alert: must be string, Alert, or *Alert")
 		}
@@ -117,9 +198,18 @@ func (aps *APS) Validate() error {
 
 	// Validate Badge
 	if aps.Badge != nil {
-		if _, ok := aps.Badge.(int); !ok {
+		badge, ok := aps.Badge.(int)
+		if !ok {
 			return fmt.Errorf("invalid type for aps.Badge: must be an integer")
 		}
+		// Zero is valid and clears the badge.
+		if badge < 0 {
+			return &ValidationError{
+				Field:   "badge",
+				Code:    CodeInvalidValue,
+				Message: fmt.Sprintf("badge must be >= 0, got %d", badge),
+			}
+		}
 	}
 
 	// Validate Sound
@@ -174,7 +264,11 @@ func (aps *APS) Validate() error {
 		case "update":
 		case "end":
 		default:
-			return fmt.Errorf("invalid value for aps.Event: %s", aps.Event)
+			return &ValidationError{
+				Field:   "event",
+				Code:    CodeInvalidValue,
+				Message: fmt.Sprintf("invalid value for aps.Event: %s", aps.Event),
+			}
 		}
 	}
 
@@ -7,9 +7,14 @@ package payload
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"slices"
 	"strconv"
 	"sync"
+	"unicode/utf8"
 
+	"github.com/takimoto3/apns/internal/bufstats"
 	"github.com/takimoto3/apns/notification"
 )
 
@@ -32,10 +37,25 @@ func (aps APS) MarshalJSONFast() ([]byte, error) {
 	ptr := apsPool.Get().(*[]byte)
 	b := (*ptr)[:0]
 	defer func() {
+		bufstats.Observe(len(b))
 		*ptr = b
 		apsPool.Put(ptr)
 	}()
 
+	b, err := aps.AppendJSONFast(b)
+	if err != nil {
+		return nil, err
+	}
+	// b is returned to the pool on exit, so hand out a copy.
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
+}
+
+// AppendJSONFast appends the JSON encoding of the APS dictionary produced by
+// MarshalJSONFast to b and returns the extended buffer, so that callers can
+// encode into a buffer of their own without an intermediate copy.
+func (aps APS) AppendJSONFast(b []byte) ([]byte, error) {
 	b = append(b, '{')
 	first := true
 
@@ -152,6 +172,9 @@ func (aps APS) MarshalJSONFast() ([]byte, error) {
 		switch v := aps.RelevanceScore.(type) {
 		case float64:
 			b = strconv.AppendFloat(b, v, 'f', -1, 64)
+		case int:
+			// Validate accepts whole-number scores as int.
+			b = strconv.AppendFloat(b, float64(v), 'f', -1, 64)
 		default:
 			return nil, ErrInvalidType
 		}
@@ -257,18 +280,101 @@ func (aps APS) MarshalJSONFast() ([]byte, error) {
 	return b, nil
 }
 
+// ErrMaxDepthExceeded is returned by EncodeValue when a value is nested more than
+// MaxEncodeDepth levels deep, which usually means it contains a cycle.
+var ErrMaxDepthExceeded = errors.New("value nested too deeply")
+
+// ErrSizeLimitExceeded is returned by EncodeValueLimited when the encoded value
+// exceeds the size limit.
+var ErrSizeLimitExceeded = errors.New("encoded value exceeds size limit")
+
+// MaxEncodeDepth is the maximum nesting depth of maps and slices EncodeValue encodes.
+const MaxEncodeDepth = 1000
+
 // EncodeValue is a helper function that recursively encodes a value into a JSON byte slice.
 // It supports basic types (string, int, float, bool), as well as nested maps and slices.
 func EncodeValue(b []byte, v any) ([]byte, error) {
+	e := valueEncoder{start: len(b)}
+	return e.encode(b, v, 0)
+}
+
+// EncodeValueLimited is like EncodeValue but stops and returns ErrSizeLimitExceeded
+// as soon as the bytes it appends to b would exceed maxBytes, which must be
+// positive; use EncodeValue for no limit. Strings and the output of
+// json.Marshaler values are checked against the limit before they are appended.
+func EncodeValueLimited(b []byte, v any, maxBytes int) ([]byte, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("size limit must be positive, got %d", maxBytes)
+	}
+	e := valueEncoder{start: len(b), maxBytes: maxBytes}
+	return e.encode(b, v, 0)
+}
+
+// valueEncoder holds the state of one EncodeValue call.
+type valueEncoder struct {
+	start    int // length of the buffer before encoding
+	maxBytes int // maximum number of bytes to append; 0 means no limit
+}
+
+// check reports ErrSizeLimitExceeded once b has grown beyond the limit.
+func (e *valueEncoder) check(b []byte) error {
+	return e.reserve(b, 0)
+}
+
+// reserve reports ErrSizeLimitExceeded if appending n more bytes to b would
+// exceed the limit.
+func (e *valueEncoder) reserve(b []byte, n int) error {
+	if e.maxBytes > 0 && len(b)-e.start+n > e.maxBytes {
+		return ErrSizeLimitExceeded
+	}
+	return nil
+}
+
+// appendString appends s as a JSON string if its unescaped length fits within
+// the limit, so that an oversized string is not copied into b.
+func (e *valueEncoder) appendString(b []byte, s string) ([]byte, error) {
+	if err := e.reserve(b, len(s)+2); err != nil {
+		return nil, err
+	}
+	b = appendString(b, s)
+	if err := e.check(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (e *valueEncoder) encode(b []byte, v any, depth int) ([]byte, error) {
+	if depth > MaxEncodeDepth {
+		return nil, ErrMaxDepthExceeded
+	}
 	switch val := v.(type) {
 	case string:
-		b = strconv.AppendQuote(b, val)
+		var err error
+		if b, err = e.appendString(b, val); err != nil {
+			return nil, err
+		}
 	case int:
 		b = strconv.AppendInt(b, int64(val), 10)
 	case int64:
 		b = strconv.AppendInt(b, val, 10)
+	case int32:
+		b = strconv.AppendInt(b, int64(val), 10)
+	case uint:
+		b = strconv.AppendUint(b, uint64(val), 10)
+	case uint64:
+		b = strconv.AppendUint(b, val, 10)
 	case float64:
-		b = strconv.AppendFloat(b, val, 'f', -1, 64)
+		var err error
+		b, err = appendFloat64(b, val)
+		if err != nil {
+			return nil, err
+		}
+	case float32:
+		var err error
+		b, err = appendFloat32(b, val)
+		if err != nil {
+			return nil, err
+		}
 	case bool:
 		if val {
 			b = append(b, "true"...)
@@ -278,7 +384,10 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 	case nil:
 		b = append(b, "null"...)
 	case []byte:
-		b = strconv.AppendQuote(b, string(val))
+		var err error
+		if b, err = e.appendString(b, string(val)); err != nil {
+			return nil, err
+		}
 	case notification.EpochTime:
 		b = strconv.AppendInt(b, int64(val), 10)
 	case *notification.EpochTime:
@@ -289,7 +398,10 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 			if i > 0 {
 				b = append(b, ',')
 			}
-			b = strconv.AppendQuote(b, v2)
+			var err error
+			if b, err = e.appendString(b, v2); err != nil {
+				return nil, err
+			}
 		}
 		b = append(b, ']')
 	case []int:
@@ -299,6 +411,9 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 				b = append(b, ',')
 			}
 			b = strconv.AppendInt(b, int64(v2), 10)
+			if err := e.check(b); err != nil {
+				return nil, err
+			}
 		}
 		b = append(b, ']')
 	case []int64:
@@ -308,6 +423,9 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 				b = append(b, ',')
 			}
 			b = strconv.AppendInt(b, v2, 10)
+			if err := e.check(b); err != nil {
+				return nil, err
+			}
 		}
 		b = append(b, ']')
 	case []float64:
@@ -316,7 +434,14 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 			if i > 0 {
 				b = append(b, ',')
 			}
-			b = strconv.AppendFloat(b, v2, 'f', -1, 64)
+			var err error
+			b, err = appendFloat64(b, v2)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.check(b); err != nil {
+				return nil, err
+			}
 		}
 		b = append(b, ']')
 	case json.Marshaler:
@@ -324,6 +449,9 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := e.reserve(b, len(marshaled)); err != nil {
+			return nil, err
+		}
 		b = append(b, marshaled...)
 	case map[string]any:
 		b = append(b, '{')
@@ -334,13 +462,40 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 			} else {
 				first = false
 			}
-			b = strconv.AppendQuote(b, k2)
-			b = append(b, ':')
 			var err error
-			b, err = EncodeValue(b, v2)
+			if b, err = e.appendString(b, k2); err != nil {
+				return nil, err
+			}
+			b = append(b, ':')
+			b, err = e.encode(b, v2, depth+1)
 			if err != nil {
 				return nil, err
 			}
+			if err := e.check(b); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, '}')
+	case map[string]string:
+		// Keys are sorted to match the output of encoding/json.
+		keys := make([]string, 0, len(val))
+		for k2 := range val {
+			keys = append(keys, k2)
+		}
+		slices.Sort(keys)
+		b = append(b, '{')
+		for i, k2 := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = e.appendString(b, k2); err != nil {
+				return nil, err
+			}
+			b = append(b, ':')
+			if b, err = e.appendString(b, val[k2]); err != nil {
+				return nil, err
+			}
 		}
 		b = append(b, '}')
 	case []any:
@@ -350,14 +505,104 @@ func EncodeValue(b []byte, v any) ([]byte, error) {
 				b = append(b, ',')
 			}
 			var err error
-			b, err = EncodeValue(b, v2)
+			b, err = e.encode(b, v2, depth+1)
 			if err != nil {
 				return nil, err
 			}
+			if err := e.check(b); err != nil {
+				return nil, err
+			}
 		}
 		b = append(b, ']')
 	default:
 		return nil, ErrInvalidType
 	}
+	if err := e.check(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// appendString appends s as a JSON string. Quotes, backslashes and control
+// characters are escaped, invalid UTF-8 is replaced with U+FFFD, and U+2028,
+// U+2029 and the HTML characters <, > and & are escaped, as encoding/json does.
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// appendFloat64 appends f in decimal notation. NaN and infinities cannot be
+// represented in JSON.
+func appendFloat64(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrInvalidType
+	}
+	return strconv.AppendFloat(b, f, 'f', -1, 64), nil
+}
+
+// appendFloat32 appends f formatted the same way encoding/json formats a float32:
+// the shortest representation, switching to exponent notation for very small or
+// large magnitudes. NaN and infinities cannot be represented in JSON.
+func appendFloat32(b []byte, f float32) ([]byte, error) {
+	f64 := float64(f)
+	if math.IsNaN(f64) || math.IsInf(f64, 0) {
+		return nil, ErrInvalidType
+	}
+	format := byte('f')
+	if abs := math.Abs(f64); abs != 0 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+		format = 'e'
+	}
+	b = strconv.AppendFloat(b, f64, format, -1, 32)
+	if format == 'e' {
+		// Clean up e-09 to e-9, as encoding/json does.
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
 	return b, nil
 }
@@ -3,7 +3,10 @@ package payload_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,6 +28,13 @@ func TestAPSMarshalJSONTo3(t *testing.T) {
 			input: payload.APS{},
 			want:  `{}`,
 		},
+		"int relevance score": {
+			input: payload.APS{
+				Alert:          "Hello",
+				RelevanceScore: 1,
+			},
+			want: `{"alert":"Hello","relevance-score":1}`,
+		},
 		"simple alert string": {
 			input: payload.APS{
 				Alert: "Hello",
@@ -370,3 +380,232 @@ func TestEncodeValue(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeValue_MatchesEncodingJSON(t *testing.T) {
+	tests := map[string]any{
+		"map_string_string":       map[string]string{"b": "2", "a": "1", "c": "three"},
+		"empty_map_string_string": map[string]string{},
+		"uint":                    uint(42),
+		"uint_zero":               uint(0),
+		"uint64_max":              uint64(18446744073709551615),
+		"int32_min":               int32(-2147483648),
+		"int32_max":               int32(2147483647),
+		"float32":                 float32(3.14),
+		"float32_negative":        float32(-0.1),
+		"float32_zero":            float32(0),
+		"float32_integer":         float32(100),
+		"float32_small":           float32(1e-7),
+		"float32_large":           float32(1e21),
+		"float32_max":             float32(3.4028235e38),
+		"nested_in_map":           map[string]any{"count": uint64(7), "ratio": float32(0.5), "tags": map[string]string{"k": "v"}},
+		"html":                    "<b>Tom & Jerry</b>",
+		"html_in_map":             map[string]string{"<key>": "a&b"},
+		"escapes":                 "quote\" backslash\\ tab\t nul\x00 line\u2028",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := payload.EncodeValue(nil, input)
+			if err != nil {
+				t.Fatalf("EncodeValue failed: %v", err)
+			}
+			want, err := json.Marshal(input)
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %v", err)
+			}
+			if name == "nested_in_map" {
+				// map[string]any keys are not sorted by EncodeValue, so compare semantically.
+				var g, w any
+				if err := json.Unmarshal(got, &g); err != nil {
+					t.Fatalf("invalid JSON from EncodeValue: %v", err)
+				}
+				_ = json.Unmarshal(want, &w)
+				if diff := cmp.Diff(w, g); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if string(got) != string(want) {
+				t.Errorf("EncodeValue(%#v) = %s, want %s", input, got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeValue_Pathological(t *testing.T) {
+	cyclic := map[string]any{}
+	cyclic["self"] = cyclic
+
+	deep := any("leaf")
+	for range payload.MaxEncodeDepth + 1 {
+		deep = []any{deep}
+	}
+
+	tests := map[string]struct {
+		input   any
+		wantErr error
+		want    string
+	}{
+		"cyclic map":          {input: cyclic, wantErr: payload.ErrMaxDepthExceeded},
+		"too deeply nested":   {input: deep, wantErr: payload.ErrMaxDepthExceeded},
+		"NaN":                 {input: math.NaN(), wantErr: payload.ErrInvalidType},
+		"infinity in slice":   {input: []float64{1, math.Inf(1)}, wantErr: payload.ErrInvalidType},
+		"invalid UTF-8":       {input: "a\xffb", want: `"a\ufffdb"`},
+		"control characters":  {input: "\x00\a\n\t", want: `"\u0000\u0007\n\t"`},
+		"line separator":      {input: "a\u2028b", want: `"a\u2028b"`},
+		"non-ASCII printable": {input: map[string]string{"キ": "値"}, want: `{"キ":"値"}`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := payload.EncodeValue(nil, tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("EncodeValue() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncodeValue() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("EncodeValue() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeValueLimited(t *testing.T) {
+	large := make(map[string]any, 1000)
+	for i := range 1000 {
+		large[fmt.Sprintf("key-%d", i)] = strings.Repeat("v", 100)
+	}
+
+	prefix := []byte(`{"data":`)
+	if _, err := payload.EncodeValueLimited(prefix, large, 4096); !errors.Is(err, payload.ErrSizeLimitExceeded) {
+		t.Errorf("EncodeValueLimited() error = %v, want %v", err, payload.ErrSizeLimitExceeded)
+	}
+
+	small := map[string]any{"a": []any{1, "two", 3.5}}
+	want, err := payload.EncodeValue(nil, small)
+	if err != nil {
+		t.Fatalf("EncodeValue() unexpected error: %v", err)
+	}
+	got, err := payload.EncodeValueLimited(prefix, small, len(want))
+	if err != nil {
+		t.Fatalf("EncodeValueLimited() unexpected error: %v", err)
+	}
+	if string(got) != string(prefix)+string(want) {
+		t.Errorf("EncodeValueLimited() = %s, want %s%s", got, prefix, want)
+	}
+	if _, err := payload.EncodeValueLimited(nil, small, len(want)-1); !errors.Is(err, payload.ErrSizeLimitExceeded) {
+		t.Errorf("EncodeValueLimited() one byte short: error = %v, want %v", err, payload.ErrSizeLimitExceeded)
+	}
+	for _, maxBytes := range []int{0, -1} {
+		if _, err := payload.EncodeValueLimited(nil, small, maxBytes); err == nil || errors.Is(err, payload.ErrSizeLimitExceeded) {
+			t.Errorf("EncodeValueLimited(%d) error = %v, want an invalid limit error", maxBytes, err)
+		}
+	}
+
+	// Oversized strings and marshaled values are rejected before they are
+	// appended, so the buffer never grows beyond the limit.
+	buf := make([]byte, 0, 64)
+	for name, v := range map[string]any{
+		"string":    strings.Repeat("s", 1<<20),
+		"key":       map[string]any{strings.Repeat("k", 1<<20): 1},
+		"marshaler": json.RawMessage(`"` + strings.Repeat("m", 1<<20) + `"`),
+	} {
+		if _, err := payload.EncodeValueLimited(buf, v, cap(buf)); !errors.Is(err, payload.ErrSizeLimitExceeded) {
+			t.Errorf("%s: EncodeValueLimited() error = %v, want %v", name, err, payload.ErrSizeLimitExceeded)
+		}
+		if allocs := testing.AllocsPerRun(10, func() { _, _ = payload.EncodeValueLimited(buf, v, cap(buf)) }); name != "marshaler" && allocs > 0 {
+			t.Errorf("%s: EncodeValueLimited() allocated %v times, want the string not to be copied", name, allocs)
+		}
+	}
+}
+
+func FuzzEncodeValue(f *testing.F) {
+	f.Add("hello", int64(1), 1.5, true, 3, 16)
+	f.Add("a\xff\x00\"\\", int64(-1), -0.0, false, 50, 4096)
+	f.Add("\u2028", int64(0), 1e300, true, 1, 1)
+
+	f.Fuzz(func(t *testing.T, s string, i int64, fl float64, bl bool, depth, maxBytes int) {
+		var v any = map[string]any{s: []any{s, i, fl, bl, nil, map[string]string{s: s}}}
+		for range depth % 64 {
+			v = []any{v, s}
+		}
+
+		b, err := payload.EncodeValue(nil, v)
+		if err != nil {
+			if math.IsNaN(fl) || math.IsInf(fl, 0) {
+				return
+			}
+			t.Fatalf("EncodeValue() unexpected error: %v", err)
+		}
+		if !json.Valid(b) {
+			t.Fatalf("EncodeValue() produced invalid JSON: %q", b)
+		}
+
+		limited, err := payload.EncodeValueLimited(nil, v, maxBytes)
+		switch {
+		case maxBytes <= 0:
+			if err == nil {
+				t.Fatalf("EncodeValueLimited(%d) succeeded, want an invalid limit error", maxBytes)
+			}
+		case len(b) > maxBytes:
+			if !errors.Is(err, payload.ErrSizeLimitExceeded) {
+				t.Fatalf("EncodeValueLimited(%d) error = %v for %d bytes, want %v", maxBytes, err, len(b), payload.ErrSizeLimitExceeded)
+			}
+		case err != nil:
+			t.Fatalf("EncodeValueLimited(%d) unexpected error: %v", maxBytes, err)
+		case len(limited) != len(b):
+			t.Fatalf("EncodeValueLimited() = %d bytes, EncodeValue() = %d bytes", len(limited), len(b))
+		}
+	})
+}
+
+func TestBufferHighWaterMark(t *testing.T) {
+	aps := payload.APS{Alert: strings.Repeat("x", 8192)}
+	b, err := aps.MarshalJSONFast()
+	if err != nil {
+		t.Fatalf("MarshalJSONFast failed: %v", err)
+	}
+	if got := payload.BufferHighWaterMark(); got < len(b) {
+		t.Errorf("BufferHighWaterMark() = %d, want at least %d", got, len(b))
+	}
+}
+
+func TestAPS_AppendJSONFast(t *testing.T) {
+	aps := payload.APS{Alert: "hello", Badge: 1, ContentState: map[string]any{"k": "v"}}
+	want, err := aps.MarshalJSONFast()
+	if err != nil {
+		t.Fatalf("MarshalJSONFast failed: %v", err)
+	}
+	got, err := aps.AppendJSONFast([]byte("prefix:"))
+	if err != nil {
+		t.Fatalf("AppendJSONFast failed: %v", err)
+	}
+	if string(got) != "prefix:"+string(want) {
+		t.Errorf("AppendJSONFast() = %s, want prefix:%s", got, want)
+	}
+}
+
+func TestAPS_MarshalJSONFast_IntRelevanceScore(t *testing.T) {
+	for _, score := range []any{1, 0, 1.0, 0.5} {
+		aps := payload.APS{Alert: "Hello", RelevanceScore: score}
+		if err := aps.Validate(); err != nil {
+			t.Fatalf("Validate(%v) failed: %v", score, err)
+		}
+		got, err := aps.MarshalJSONFast()
+		if err != nil {
+			t.Fatalf("MarshalJSONFast(%v) failed: %v", score, err)
+		}
+		want, err := json.Marshal(score)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(got, append([]byte(`"relevance-score":`), want...)) {
+			t.Errorf("MarshalJSONFast(%v) = %s, want relevance-score %s", score, got, want)
+		}
+	}
+}
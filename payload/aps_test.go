@@ -1,6 +1,7 @@
 package payload_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/payload"
 	"github.com/takimoto3/apns/payload/interruptionlevel"
+	"github.com/takimoto3/apns/payload/sound"
 )
 
 func TestAPSValidate(t *testing.T) {
@@ -81,6 +83,24 @@ func TestAPSValidate(t *testing.T) {
 			},
 			wantErrString: "invalid type for aps.Badge",
 		},
+		"invalid_badge_negative": {
+			aps: payload.APS{
+				Badge: -1,
+			},
+			wantErrString: "badge must be >= 0",
+		},
+		"valid_badge_zero": {
+			aps: payload.APS{
+				Badge: 0, // Clears the badge
+			},
+			wantErrString: "",
+		},
+		"valid_badge_positive": {
+			aps: payload.APS{
+				Badge: 5,
+			},
+			wantErrString: "",
+		},
 		"invalid_sound_type": {
 			aps: payload.APS{
 				Sound: true, // Should be string, Sound, or *Sound
@@ -189,3 +209,229 @@ func TestAPSValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestAPSValidate_ValidationError(t *testing.T) {
+	aps := payload.APS{
+		ContentState: map[string]any{"status": "running"},
+		Event:        "bad-event",
+	}
+	err := aps.Validate()
+	var verr *payload.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("APS.Validate() error = %v (%T), want *payload.ValidationError", err, err)
+	}
+	if verr.Field != "event" || verr.Code != payload.CodeInvalidValue {
+		t.Errorf("ValidationError = {Field:%q Code:%q}, want {Field:%q Code:%q}", verr.Field, verr.Code, "event", payload.CodeInvalidValue)
+	}
+}
+
+func TestAPSValidate_Alert(t *testing.T) {
+	tests := map[string]struct {
+		alert         any
+		wantErrString string
+	}{
+		"string alert":                   {alert: "Hello"},
+		"object alert with body":         {alert: payload.Alert{Body: "Hello"}},
+		"object alert with loc key":      {alert: &payload.Alert{LocKey: "GREETING", LocArgs: []string{"Alice"}}},
+		"object alert with body and loc": {alert: &payload.Alert{Body: "Hello", LocKey: "GREETING"}},
+		"title loc key only":             {alert: &payload.Alert{TitleLocKey: "TITLE"}},
+		"map alert":                      {alert: map[string]any{"loc-key": "GREETING"}, wantErrString: "invalid type for aps.Alert"},
+		"nil object alert":               {alert: (*payload.Alert)(nil), wantErrString: "must not be a nil *Alert"},
+		"loc args without key":           {alert: &payload.Alert{Body: "Hello", LocArgs: []string{"Alice"}}, wantErrString: "loc-args requires loc-key"},
+		"title loc args without key":     {alert: &payload.Alert{Title: "Hi", TitleLocArgs: []string{"Bob"}}, wantErrString: "title-loc-args requires title-loc-key"},
+		"subtitle loc args without key":  {alert: &payload.Alert{Body: "Hi", SubtitleLocArgs: []string{"Carol"}}, wantErrString: "subtitle-loc-args requires subtitle-loc-key"},
+		"all loc args with keys": {alert: &payload.Alert{
+			LocKey: "BODY", LocArgs: []string{"Alice"},
+			TitleLocKey: "TITLE", TitleLocArgs: []string{"Bob"},
+			SubtitleLocKey: "SUBTITLE", SubtitleLocArgs: []string{"Carol"},
+		}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			aps := payload.APS{Alert: tt.alert}
+			err := aps.Validate()
+			if tt.wantErrString == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrString) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErrString)
+			}
+		})
+	}
+}
+
+func TestAPSValidateStrict_Critical(t *testing.T) {
+	tests := map[string]struct {
+		aps       payload.APS
+		wantField string // If non-empty, a critical_mismatch error on this field is expected
+	}{
+		"critical level with critical sound": {
+			aps: payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Critical, Sound: &payload.Sound{Name: "default", Critical: sound.Critical, Volume: 1.0}},
+		},
+		"critical level with critical sound value": {
+			aps: payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Critical, Sound: payload.Sound{Name: "default", Critical: sound.Critical}},
+		},
+		"critical sound without level": {
+			aps: payload.APS{Alert: "alert", Sound: &payload.Sound{Name: "default", Critical: sound.Critical}},
+		},
+		"regular level with regular sound": {
+			aps: payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Active, Sound: "default"},
+		},
+		"critical level with string sound": {
+			aps:       payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Critical, Sound: "default"},
+			wantField: "sound",
+		},
+		"critical level without sound": {
+			aps:       payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Critical},
+			wantField: "sound",
+		},
+		"critical level with non-critical sound": {
+			aps:       payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Critical, Sound: &payload.Sound{Name: "default"}},
+			wantField: "sound",
+		},
+		"critical sound with active level": {
+			aps:       payload.APS{Alert: "alert", InterruptionLevel: interruptionlevel.Active, Sound: &payload.Sound{Name: "default", Critical: sound.Critical}},
+			wantField: "interruption-level",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := tt.aps.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			err := tt.aps.ValidateStrict()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("ValidateStrict() unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateStrict() error = %v, want *payload.ValidationError", err)
+			}
+			if verr.Field != tt.wantField || verr.Code != payload.CodeCriticalMismatch {
+				t.Errorf("ValidationError = {Field:%q Code:%q}, want {Field:%q Code:%q}", verr.Field, verr.Code, tt.wantField, payload.CodeCriticalMismatch)
+			}
+		})
+	}
+}
+
+func TestAPSValidateStrict_ContentAvailableWithAlert(t *testing.T) {
+	tests := map[string]struct {
+		aps      payload.APS
+		wantWarn bool
+	}{
+		"content-available alone":     {aps: payload.APS{ContentAvailable: 1}},
+		"alert alone":                 {aps: payload.APS{Alert: "hello"}},
+		"content-available and badge": {aps: payload.APS{ContentAvailable: 1, Badge: 1}},
+		"content-available and alert": {aps: payload.APS{ContentAvailable: 1, Alert: "hello"}, wantWarn: true},
+		"content-available and alert object": {
+			aps:      payload.APS{ContentAvailable: 1, Alert: &payload.Alert{Title: "Hi", Body: "hello"}},
+			wantWarn: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := tt.aps.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			err := tt.aps.ValidateStrict()
+			if !tt.wantWarn {
+				if err != nil {
+					t.Errorf("ValidateStrict() unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateStrict() error = %v, want *payload.ValidationError", err)
+			}
+			if verr.Field != "content-available" || verr.Code != payload.CodeContentAvailableWithAlert {
+				t.Errorf("ValidationError = {Field:%q Code:%q}, want {Field:%q Code:%q}", verr.Field, verr.Code, "content-available", payload.CodeContentAvailableWithAlert)
+			}
+		})
+	}
+}
+
+func TestAPSValidateStrict_AttributesOnlyForStart(t *testing.T) {
+	state := map[string]any{"score": 1}
+	attrs := map[string]any{"team": "home"}
+	tests := map[string]struct {
+		aps       payload.APS
+		wantField string // If non-empty, an attributes_not_start error on this field is expected
+	}{
+		"attributes on start": {
+			aps: payload.APS{Event: "start", ContentState: state, AttributesType: "MatchAttributes", Attributes: attrs},
+		},
+		"attributes absent on update": {
+			aps: payload.APS{Event: "update", ContentState: state},
+		},
+		"attributes absent on end": {
+			aps: payload.APS{Event: "end", ContentState: state},
+		},
+		"attributes on update": {
+			aps:       payload.APS{Event: "update", ContentState: state, AttributesType: "MatchAttributes", Attributes: attrs},
+			wantField: "attributes",
+		},
+		"attributes on end": {
+			aps:       payload.APS{Event: "end", ContentState: state, Attributes: attrs},
+			wantField: "attributes",
+		},
+		"attributes-type on update": {
+			aps:       payload.APS{Event: "update", ContentState: state, AttributesType: "MatchAttributes"},
+			wantField: "attributes-type",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := tt.aps.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			err := tt.aps.ValidateStrict()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("ValidateStrict() unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *payload.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateStrict() error = %v, want *payload.ValidationError", err)
+			}
+			if verr.Field != tt.wantField || verr.Code != payload.CodeAttributesNotStart {
+				t.Errorf("ValidationError = {Field:%q Code:%q}, want {Field:%q Code:%q}", verr.Field, verr.Code, tt.wantField, payload.CodeAttributesNotStart)
+			}
+		})
+	}
+}
+
+func TestAlertValidate_LocArgsWithoutKey(t *testing.T) {
+	tests := map[string]struct {
+		alert     payload.Alert
+		wantField string
+	}{
+		"loc-args":          {alert: payload.Alert{Body: "Hello", LocArgs: []string{"Alice"}}, wantField: "loc-key"},
+		"title-loc-args":    {alert: payload.Alert{Title: "Hi", TitleLocArgs: []string{"Bob"}}, wantField: "title-loc-key"},
+		"subtitle-loc-args": {alert: payload.Alert{Body: "Hi", SubtitleLocArgs: []string{"Carol"}}, wantField: "subtitle-loc-key"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var verr *payload.ValidationError
+			if err := tt.alert.Validate(); !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want *payload.ValidationError", err)
+			}
+			if verr.Field != tt.wantField || verr.Code != payload.CodeLocArgsWithoutKey {
+				t.Errorf("ValidationError = {Field:%q Code:%q}, want {Field:%q Code:%q}", verr.Field, verr.Code, tt.wantField, payload.CodeLocArgsWithoutKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// package payload provides types for constructing the payload of an APNs notification.
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalJSON decodes the `aps` dictionary. A dictionary alert or sound is
+// decoded into an *Alert or a *Sound and a string into a string; numbers and
+// the values of ContentState and Attributes are decoded as by DecodeValue, so
+// that, for example, a badge is an int as Validate expects.
+func (aps *APS) UnmarshalJSON(data []byte) error {
+	type plain APS // without the UnmarshalJSON method
+	var raw struct {
+		plain
+		Alert            json.RawMessage `json:"alert"`
+		Badge            json.RawMessage `json:"badge"`
+		Sound            json.RawMessage `json:"sound"`
+		ContentAvailable json.RawMessage `json:"content-available"`
+		MutableContent   json.RawMessage `json:"mutable-content"`
+		RelevanceScore   json.RawMessage `json:"relevance-score"`
+		ContentState     json.RawMessage `json:"content-state"`
+		Attributes       json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*aps = APS(raw.plain)
+
+	var err error
+	if aps.Alert, err = decodeStringOr[Alert](raw.Alert); err != nil {
+		return fmt.Errorf("invalid aps.alert: %w", err)
+	}
+	if aps.Sound, err = decodeStringOr[Sound](raw.Sound); err != nil {
+		return fmt.Errorf("invalid aps.sound: %w", err)
+	}
+	for _, f := range []struct {
+		name string
+		raw  json.RawMessage
+		dst  *any
+	}{
+		{"badge", raw.Badge, &aps.Badge},
+		{"content-available", raw.ContentAvailable, &aps.ContentAvailable},
+		{"mutable-content", raw.MutableContent, &aps.MutableContent},
+		{"relevance-score", raw.RelevanceScore, &aps.RelevanceScore},
+	} {
+		if len(f.raw) == 0 {
+			continue
+		}
+		if *f.dst, err = DecodeValue(f.raw); err != nil {
+			return fmt.Errorf("invalid aps.%s: %w", f.name, err)
+		}
+	}
+	if aps.ContentState, err = decodeMap(raw.ContentState); err != nil {
+		return fmt.Errorf("invalid aps.content-state: %w", err)
+	}
+	if aps.Attributes, err = decodeMap(raw.Attributes); err != nil {
+		return fmt.Errorf("invalid aps.attributes: %w", err)
+	}
+	return nil
+}
+
+// decodeStringOr decodes a JSON string into a string and a JSON object into a
+// *T. An absent or null value is decoded into nil.
+func decodeStringOr[T any](data json.RawMessage) (any, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	switch data[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	case '{':
+		v := new(T)
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("must be a string or an object, got %s", data)
+	}
+}
+
+// decodeMap decodes a JSON object as by DecodeValue. An absent or null value
+// is decoded into nil.
+func decodeMap(data json.RawMessage) (map[string]any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	v, err := DecodeValue(data)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be an object, got %s", data)
+	}
+	return m, nil
+}
@@ -0,0 +1,82 @@
+package payload_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestAPS_UnmarshalJSON(t *testing.T) {
+	data := `{"alert":{"title":"t","body":"b"},"badge":2,"sound":"default","content-available":1,` +
+		`"relevance-score":1,"attributes-type":"Score","attributes":{"id":9}}`
+	var aps payload.APS
+	if err := json.Unmarshal([]byte(data), &aps); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := payload.APS{
+		Alert:            &payload.Alert{Title: "t", Body: "b"},
+		Badge:            2,
+		Sound:            "default",
+		ContentAvailable: 1,
+		RelevanceScore:   1,
+		AttributesType:   "Score",
+		Attributes:       map[string]any{"id": 9},
+	}
+	if diff := cmp.Diff(want, aps); diff != "" {
+		t.Errorf("APS mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPS_UnmarshalJSON_AlertAndSoundForms(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want payload.APS
+	}{
+		"string alert and sound": {
+			`{"alert":"Hello","sound":"default"}`,
+			payload.APS{Alert: "Hello", Sound: "default"},
+		},
+		"object alert and sound": {
+			`{"alert":{"title":"Hi","loc-key":"GREETING","loc-args":["Jenna"]},"sound":{"name":"alarm.caf","critical":1,"volume":0.5}}`,
+			payload.APS{
+				Alert: &payload.Alert{Title: "Hi", LocKey: "GREETING", LocArgs: []string{"Jenna"}},
+				Sound: &payload.Sound{Name: "alarm.caf", Critical: 1, Volume: 0.5},
+			},
+		},
+		"string alert and object sound": {
+			`{"alert":"Hello","sound":{"name":"chime.caf"}}`,
+			payload.APS{Alert: "Hello", Sound: &payload.Sound{Name: "chime.caf"}},
+		},
+		"null alert": {
+			`{"alert":null,"badge":0}`,
+			payload.APS{Badge: 0},
+		},
+		"epoch times": {
+			`{"event":"end","timestamp":1700000000,"stale-date":1700003600,"dismissal-date":1700007200,"content-state":{"score":3}}`,
+			payload.APS{
+				Event:         "end",
+				Timestamp:     notification.EpochTimeFromUnix(1700000000),
+				StaleDate:     notification.EpochTimeFromUnix(1700003600),
+				DismissalDate: 1700007200,
+				ContentState:  map[string]any{"score": 3},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var aps payload.APS
+			if err := json.Unmarshal([]byte(tt.in), &aps); err != nil {
+				t.Fatalf("Unmarshal(%s) failed: %v", tt.in, err)
+			}
+			if diff := cmp.Diff(tt.want, aps); diff != "" {
+				t.Errorf("APS mismatch (-want +got):\n%s", diff)
+			}
+			if err := aps.Validate(); err != nil {
+				t.Errorf("Validate of unmarshaled APS failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+// package payload provides types for constructing the payload of an APNs notification.
+package payload
+
+import "github.com/takimoto3/apns/internal/bufstats"
+
+// BufferHighWaterMark returns the largest number of bytes written to a pooled
+// marshaling buffer since the program started. It covers the buffers of the
+// APS and Alert encoders and of the CustomData encoder of apns.Payload.
+// Initial buffer sizes at or above this value avoid reallocations.
+func BufferHighWaterMark() int {
+	return bufstats.HighWaterMark()
+}
@@ -0,0 +1,94 @@
+// package payload provides types for constructing the payload of an APNs notification.
+package payload
+
+import "slices"
+
+// Clone returns a deep copy of the APS dictionary. The alert and sound
+// dictionaries, the time pointers and the ContentState and Attributes maps are
+// copied, so the clone can be modified without affecting the original.
+func (a APS) Clone() APS {
+	c := a
+	switch v := a.Alert.(type) {
+	case Alert:
+		c.Alert = v.clone()
+	case *Alert:
+		if v != nil {
+			alert := v.clone()
+			c.Alert = &alert
+		}
+	}
+	if v, ok := a.Sound.(*Sound); ok && v != nil {
+		s := *v
+		c.Sound = &s
+	}
+	if a.StaleDate != nil {
+		t := *a.StaleDate
+		c.StaleDate = &t
+	}
+	if a.Timestamp != nil {
+		t := *a.Timestamp
+		c.Timestamp = &t
+	}
+	c.ContentState = CloneMap(a.ContentState)
+	c.Attributes = CloneMap(a.Attributes)
+	return c
+}
+
+func (a Alert) clone() Alert {
+	c := a
+	c.LocArgs = slices.Clone(a.LocArgs)
+	c.TitleLocArgs = slices.Clone(a.TitleLocArgs)
+	c.SubtitleLocArgs = slices.Clone(a.SubtitleLocArgs)
+	return c
+}
+
+// CloneMap returns a deep copy of m. Nested maps and slices of the types
+// supported by EncodeValue are copied recursively; other values are copied as is.
+// A nil map is returned as nil.
+func CloneMap(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = cloneValue(v)
+	}
+	return c
+}
+
+func cloneValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return CloneMap(val)
+	case map[string]string:
+		if val == nil {
+			return val
+		}
+		c := make(map[string]string, len(val))
+		for k, s := range val {
+			c[k] = s
+		}
+		return c
+	case []any:
+		if val == nil {
+			return val
+		}
+		c := make([]any, len(val))
+		for i, e := range val {
+			c[i] = cloneValue(e)
+		}
+		return c
+	case []string:
+		return slices.Clone(val)
+	case []int:
+		return slices.Clone(val)
+	case []int64:
+		return slices.Clone(val)
+	case []float64:
+		return slices.Clone(val)
+	case []byte:
+		return slices.Clone(val)
+	default:
+		return v
+	}
+}
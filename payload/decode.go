@@ -0,0 +1,47 @@
+// package payload provides types for constructing the payload of an APNs notification.
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// DecodeValue decodes a JSON value into the types EncodeValue encodes: objects
+// into map[string]any, arrays into []any, integers into int, other numbers into
+// float64, and strings, booleans and null as encoding/json does. Integers that
+// do not fit in an int are decoded as float64.
+func DecodeValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return coerceNumbers(v), nil
+}
+
+// coerceNumbers replaces the json.Number values in v, recursively, with an int
+// or a float64.
+func coerceNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.Atoi(v.String()); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = coerceNumbers(e)
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = coerceNumbers(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
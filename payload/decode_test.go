@@ -0,0 +1,39 @@
+package payload_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestDecodeValue(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want any
+	}{
+		"integer":          {`42`, 42},
+		"negative integer": {`-1`, -1},
+		"float":            {`0.5`, 0.5},
+		"exponent":         {`1e3`, 1000.0},
+		"overflow":         {`18446744073709551616`, math.Exp2(64)},
+		"string":           {`"x"`, "x"},
+		"null":             {`null`, nil},
+		"nested": {
+			`{"a":[1,{"b":2.5}],"c":true}`,
+			map[string]any{"a": []any{1, map[string]any{"b": 2.5}}, "c": true},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := payload.DecodeValue([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("DecodeValue(%s) failed: %v", tt.in, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DecodeValue(%s) mismatch (-want +got):\n%s", tt.in, diff)
+			}
+		})
+	}
+}
@@ -10,7 +10,7 @@ type Ratio float64
 
 // Validate checks if the ratio is within the valid range [0.0, 1.0].
 func (r Ratio) Validate() error {
-	if r < 0.0 || r > 1.0 {
+	if !(r >= 0.0 && r <= 1.0) { // also rejects NaN
 		return fmt.Errorf("ratio out of range: %f", r)
 	}
 	return nil
@@ -2,6 +2,7 @@
 package payload
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/takimoto3/apns/payload/sound"
@@ -23,10 +24,36 @@ type Sound struct {
 	Critical sound.AlertFlag `json:"critical,omitempty"`
 
 	// Volume is the volume of the sound, specified as a float between 0.0 and 1.0.
-	// This property is only used for critical alerts.
+	// This property is only used for critical alerts. A value of 0 is omitted,
+	// so the sound plays at full volume.
 	Volume Ratio `json:"volume,omitempty"`
 }
 
+// NewSound returns a regular (non-critical) Sound that plays the named sound
+// file from the app's bundle, or the system sound for "default".
+func NewSound(name string) *Sound {
+	return &Sound{Name: name}
+}
+
+// NewCriticalSound returns a Sound for a critical alert that plays the named
+// sound file at the given volume, greater than 0.0 and at most 1.0 (full volume).
+// It returns an error if the name is empty or the volume is out of range. A
+// volume of 0 is rejected rather than silencing the alert: Volume is omitted
+// from the payload when it is 0, so the sound would play at full volume.
+func NewCriticalSound(name string, volume float64) (*Sound, error) {
+	if name == "" {
+		return nil, errors.New("critical sound requires a name, e.g. \"default\"")
+	}
+	if volume == 0 {
+		return nil, errors.New("critical sound volume must be greater than 0")
+	}
+	s := &Sound{Name: name, Critical: sound.Critical, Volume: Ratio(volume)}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 // Validate checks if the values of the Sound fields are valid.
 // It ensures that the Critical flag is either 0 or 1, and that the Volume is within
 // the valid range [0.0, 1.0].
@@ -1,10 +1,12 @@
 package payload_test
 
 import (
+	"math"
 	"strings"
 	"testing"
 
 	"github.com/takimoto3/apns/payload"
+	"github.com/takimoto3/apns/payload/sound"
 )
 
 func TestSoundValidate(t *testing.T) {
@@ -56,3 +58,65 @@ func TestSoundValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSound(t *testing.T) {
+	s := payload.NewSound("ping.aiff")
+	if s.Name != "ping.aiff" || s.Critical != sound.None || s.Volume != 0 {
+		t.Errorf("NewSound() = %+v, want a regular sound named ping.aiff", *s)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("NewSound().Validate() unexpected error: %v", err)
+	}
+	b, err := s.MarshalJSONFast()
+	if err != nil {
+		t.Fatalf("MarshalJSONFast() failed: %v", err)
+	}
+	if want := `{"name":"ping.aiff"}`; string(b) != want {
+		t.Errorf("MarshalJSONFast() = %s, want %s", b, want)
+	}
+}
+
+func TestNewCriticalSound(t *testing.T) {
+	tests := map[string]struct {
+		name          string
+		volume        float64
+		want          string // expected MarshalJSONFast output
+		wantErrString string // If non-empty, an error is expected, and this string should be in the error message
+	}{
+		"full volume":      {name: "default", volume: 1.0, want: `{"critical":1,"name":"default","volume":1}`},
+		"half volume":      {name: "alarm.aiff", volume: 0.5, want: `{"critical":1,"name":"alarm.aiff","volume":0.5}`},
+		"zero volume":      {name: "default", volume: 0, wantErrString: "volume must be greater than 0"},
+		"volume too low":   {name: "default", volume: -0.1, wantErrString: "ratio out of range"},
+		"volume too high":  {name: "default", volume: 1.5, wantErrString: "ratio out of range"},
+		"volume NaN":       {name: "default", volume: math.NaN(), wantErrString: "ratio out of range"},
+		"empty sound name": {name: "", volume: 1.0, wantErrString: "critical sound requires a name"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := payload.NewCriticalSound(tt.name, tt.volume)
+			if tt.wantErrString != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrString) {
+					t.Errorf("NewCriticalSound() error = %v, want error containing %q", err, tt.wantErrString)
+				}
+				if s != nil {
+					t.Errorf("NewCriticalSound() = %+v, want nil on error", *s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCriticalSound() unexpected error: %v", err)
+			}
+			if s.Critical != sound.Critical {
+				t.Errorf("NewCriticalSound().Critical = %d, want %d", s.Critical, sound.Critical)
+			}
+			b, err := s.MarshalJSONFast()
+			if err != nil {
+				t.Fatalf("MarshalJSONFast() failed: %v", err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("MarshalJSONFast() = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+//go:build !use_std_json
+// +build !use_std_json
+
+// package payload provides types for constructing the payload of an APNs notification.
+package payload
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Ellipsis is appended to an alert body shortened by TruncateBody.
+const Ellipsis = "…"
+
+// ErrAlertTooLarge is returned by TruncateBody when the alert does not fit in
+// the given size even with its body shortened to Ellipsis alone.
+var ErrAlertTooLarge = errors.New("alert does not fit even with its body truncated")
+
+// TruncateBody shortens the Body of the alert so that the alert, as encoded by
+// MarshalJSONFast, takes at most maxBytes bytes. The body is cut on a UTF-8
+// rune boundary, trailing white space is removed and Ellipsis is appended, so
+// that as much of the body as possible is kept. It reports whether the body was
+// shortened; an alert that already fits is left unchanged. If the alert does
+// not fit even with the body reduced to Ellipsis, it is left unchanged and
+// ErrAlertTooLarge is returned.
+//
+// maxBytes is the room for the alert dictionary alone; to make a whole payload
+// fit the APNs size limit, use apns.Payload.TruncateAlertBody, which subtracts
+// the size of the rest of the payload.
+func TruncateBody(a *Alert, maxBytes int) (bool, error) {
+	size := func(body string) (int, error) {
+		c := *a
+		c.Body = body
+		b, err := c.MarshalJSONFast()
+		return len(b), err
+	}
+	n, err := size(a.Body)
+	if err != nil {
+		return false, err
+	}
+	if n <= maxBytes {
+		return false, nil
+	}
+
+	// The rune boundaries of the body, each a possible cut. The encoded size
+	// only grows with the length of the kept prefix, so the longest prefix that
+	// fits is found by binary search.
+	var cuts []int
+	for i := range a.Body {
+		cuts = append(cuts, i)
+	}
+	shortened := func(cut int) string {
+		return strings.TrimRightFunc(a.Body[:cut], unicode.IsSpace) + Ellipsis
+	}
+	best := -1
+	lo, hi := 0, len(cuts)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		n, err := size(shortened(cuts[mid]))
+		if err != nil {
+			return false, err
+		}
+		if n <= maxBytes {
+			best = mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	if best < 0 {
+		return false, ErrAlertTooLarge
+	}
+	a.Body = shortened(cuts[best])
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package payload_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestTruncateBody(t *testing.T) {
+	tests := map[string]struct {
+		alert    payload.Alert
+		maxBytes int
+		want     string
+	}{
+		"fits": {
+			alert:    payload.Alert{Title: "t", Body: "short"},
+			maxBytes: 100,
+			want:     "short",
+		},
+		"ascii": {
+			alert:    payload.Alert{Body: "The quick brown fox"},
+			maxBytes: len(`{"body":"The quick…"}`),
+			want:     "The quick…",
+		},
+		"japanese": {
+			// Each of these runes is 3 bytes in UTF-8.
+			alert:    payload.Alert{Body: "こんにちは世界"},
+			maxBytes: len(`{"body":"こんにち…"}`) + 2,
+			want:     "こんにち…",
+		},
+		"emoji": {
+			// Each emoji is 4 bytes in UTF-8.
+			alert:    payload.Alert{Title: "Hi", Body: "🎉🎉🎉🎉🎉"},
+			maxBytes: len(`{"title":"Hi","body":"🎉🎉…"}`),
+			want:     "🎉🎉…",
+		},
+		"escaped characters": {
+			alert:    payload.Alert{Body: `a"b"c"d"e`},
+			maxBytes: len(`{"body":"a\"b\"…"}`),
+			want:     `a"b"…`,
+		},
+		"trailing space": {
+			alert:    payload.Alert{Body: "hello world again"},
+			maxBytes: len(`{"body":"hello w…"}`) - 1,
+			want:     "hello…",
+		},
+		"only ellipsis": {
+			alert:    payload.Alert{Body: "äöü"},
+			maxBytes: len(`{"body":"…"}`),
+			want:     "…",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := tt.alert
+			truncated, err := payload.TruncateBody(&a, tt.maxBytes)
+			if err != nil {
+				t.Fatalf("TruncateBody failed: %v", err)
+			}
+			if a.Body != tt.want {
+				t.Errorf("Body = %q, want %q", a.Body, tt.want)
+			}
+			if truncated != (tt.want != tt.alert.Body) {
+				t.Errorf("truncated = %t, want %t", truncated, !truncated)
+			}
+			if !utf8.ValidString(a.Body) {
+				t.Errorf("Body %q is not valid UTF-8", a.Body)
+			}
+			b, err := a.MarshalJSONFast()
+			if err != nil {
+				t.Fatalf("MarshalJSONFast failed: %v", err)
+			}
+			if len(b) > tt.maxBytes {
+				t.Errorf("alert takes %d bytes, more than %d: %s", len(b), tt.maxBytes, b)
+			}
+		})
+	}
+}
+
+func TestTruncateBody_TooLarge(t *testing.T) {
+	a := payload.Alert{Title: strings.Repeat("t", 50), Body: "body"}
+	if _, err := payload.TruncateBody(&a, 40); !errors.Is(err, payload.ErrAlertTooLarge) {
+		t.Errorf("TruncateBody error = %v, want %v", err, payload.ErrAlertTooLarge)
+	}
+	if a.Body != "body" {
+		t.Errorf("Body = %q, want it unchanged", a.Body)
+	}
+}
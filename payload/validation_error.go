@@ -0,0 +1,73 @@
+// package payload provides types for constructing the payload of an APNs notification.
+package payload
+
+// Validation error codes reported in ValidationError.Code.
+const (
+	// CodeRequiredForUpdate indicates that a field required for a Live Activity
+	// `update` event is missing.
+	CodeRequiredForUpdate = "required_for_update"
+	// CodeRequiredForStart indicates that a field required for a Live Activity
+	// `start` event is missing.
+	CodeRequiredForStart = "required_for_start"
+	// CodeRequiredForEnd indicates that a field required for a Live Activity
+	// `end` event is missing.
+	CodeRequiredForEnd = "required_for_end"
+	// CodeRequired indicates that a field required by the push type is missing.
+	CodeRequired = "required"
+	// CodeLocArgsWithoutKey indicates that alert localization arguments, e.g.
+	// loc-args, are set without their localization key, e.g. loc-key.
+	CodeLocArgsWithoutKey = "loc_args_without_key"
+	// CodeVoIPContentAvailable indicates that a `voip` push sets content-available,
+	// which belongs to silent background pushes; a VoIP push must report a call.
+	CodeVoIPContentAvailable = "voip_content_available"
+	// CodeTopicSuffixMismatch indicates that the bundle ID already ends with the
+	// topic suffix of another push type, so the topic would carry both suffixes.
+	CodeTopicSuffixMismatch = "topic_suffix_mismatch"
+	// CodeDoubledTopicSuffix indicates that the bundle ID already ends with the
+	// topic suffix of its own push type, so the topic would repeat the suffix.
+	// It is reported by strict validation only.
+	CodeDoubledTopicSuffix = "doubled_topic_suffix"
+	// CodeInvalidValue indicates that a field has a value outside of the allowed set.
+	CodeInvalidValue = "invalid_value"
+	// CodeBackgroundUserContent indicates that a `background` push carries an
+	// alert, sound or badge, which would make it user-visible.
+	CodeBackgroundUserContent = "background_user_content"
+	// CodeCriticalMismatch indicates that the critical interruption level and the
+	// critical sound flag are not used together. It is reported by ValidateStrict only.
+	CodeCriticalMismatch = "critical_mismatch"
+	// CodeBackgroundPushType indicates that a silent content-available push is not
+	// sent with the `background` push type. It is reported by strict validation only.
+	CodeBackgroundPushType = "background_push_type"
+	// CodeContentAvailableWithAlert indicates that content-available is combined
+	// with an alert; the alert is shown, but the app is not reliably woken in the
+	// background. It is reported by ValidateStrict only.
+	CodeContentAvailableWithAlert = "content_available_with_alert"
+	// CodeAttributesNotStart indicates that Live Activity attributes are set on an
+	// event other than `start`, where they are ignored. It is reported by
+	// ValidateStrict only.
+	CodeAttributesNotStart = "attributes_not_start"
+	// CodeUppercaseBundleID indicates that a bundle ID contains uppercase letters,
+	// which is usually a copy-paste error. It is reported by strict validation only.
+	CodeUppercaseBundleID = "uppercase_bundle_id"
+	// CodeExpirationTooFar indicates that an expiration is implausibly far in the
+	// future, which usually means it was given in milliseconds instead of seconds.
+	CodeExpirationTooFar = "expiration_too_far"
+)
+
+// ValidationError describes a validation failure of a single field.
+// Field is the JSON key of the offending field and Code is a stable identifier
+// of the rule that failed, so that callers can handle failures programmatically
+// (e.g. map them to API error responses) without parsing the message.
+type ValidationError struct {
+	// Field is the JSON key of the field that failed validation, e.g. "content-state".
+	Field string
+	// Code identifies the validation rule that failed, e.g. "required_for_update".
+	Code string
+	// Message is a human-readable description of the failure.
+	Message string
+}
+
+// Error returns the human-readable message of the validation failure.
+func (e *ValidationError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,31 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import "sync"
+
+// sharedBody holds the payload marshaled for the notifications created from a
+// template, so that sending the template to many tokens marshals its payload
+// only once. It lives as long as the template and its copies.
+type sharedBody struct {
+	mu      sync.Mutex
+	payload *Payload
+	body    []byte
+}
+
+// get returns the body marshaled for p, marshaling it with marshal if p is not
+// the payload the body was marshaled for, e.g. because a copy of the template
+// was given a payload of its own.
+func (s *sharedBody) get(p *Payload, marshal func(*Payload) ([]byte, error)) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.payload == p && s.body != nil {
+		return s.body, nil
+	}
+	body, err := marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	s.payload = p
+	s.body = body
+	return body, nil
+}
@@ -2,8 +2,11 @@
 package apns
 
 import (
+	"fmt"
 	"sync"
 
+	"github.com/takimoto3/apns/internal/bufstats"
+	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/payload"
 )
 
@@ -19,43 +22,120 @@ var customDataPool = sync.Pool{
 // MarshalJSONFast is a custom JSON marshaler for the Payload type that is optimized for performance.
 // It is used when the "use_std_json" build tag is not specified.
 func (p Payload) MarshalJSONFast() ([]byte, error) {
-	var err error
-	// --- 1. aps ---
-	apsBytes, err := p.APS.MarshalJSONFast()
+	ptr := customDataPool.Get().(*[]byte)
+	b := (*ptr)[:0]
+	defer func() {
+		bufstats.Observe(len(b))
+		*ptr = b
+		customDataPool.Put(ptr)
+	}()
+
+	b, err := p.appendJSONFast(b)
 	if err != nil {
 		return nil, err
 	}
-	// --- 2. CustomData ---
-	var customDataBytes []byte
-	if len(p.CustomData) > 0 {
-		ptr := customDataPool.Get().(*[]byte)
-		b := (*ptr)[:0]
-		defer func() {
-			*ptr = b
-			customDataPool.Put(ptr)
-		}()
+	// b is returned to the pool on exit, so hand out a copy.
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
+}
 
-		customDataBytes, err = marshalCustomData(b, p.CustomData)
-		if err != nil {
-			return nil, err
-		}
+// EstimatedSize returns the length in bytes of the payload as encoded by
+// MarshalJSONFast, without allocating the encoded payload. It can be used to
+// trim content before sending a payload close to the size limit. It fails
+// like MarshalJSONFast on unsupported CustomData types.
+func (p Payload) EstimatedSize() (int, error) {
+	ptr := customDataPool.Get().(*[]byte)
+	b := (*ptr)[:0]
+	defer func() {
+		bufstats.Observe(len(b))
+		*ptr = b
+		customDataPool.Put(ptr)
+	}()
+
+	b, err := p.appendJSONFast(b)
+	if err != nil {
+		return 0, err
 	}
+	return len(b), nil
+}
 
-	// Estimate buffer size: len(apsBytes) + len(customDataBytes) + 12
-	// 12 = { } + "aps": + comma + some extra margin
-	b := make([]byte, 0, len(apsBytes)+len(customDataBytes)+12)
-	b = append(b, '{')
+// Fits reports whether the payload, as encoded by MarshalJSONFast, is within
+// the size limit APNs applies to the push type. It reports false if the payload
+// cannot be encoded.
+func (p Payload) Fits(pushType notification.PushType) bool {
+	n, err := p.EstimatedSize()
+	return err == nil && checkPayloadSize(pushType, n) == nil
+}
 
-	b = append(b, `"aps":`...)
-	b = append(b, apsBytes...)
+// TruncateAlertBody shortens the body of the alert with payload.TruncateBody so
+// that the payload, as encoded by MarshalJSONFast, takes at most maxBytes bytes,
+// e.g. MaxPayloadSize. The rest of the payload is not modified. The alert may be
+// a string, a payload.Alert or a *payload.Alert; a string alert is shortened
+// like the body of an alert dictionary. It reports whether the body was
+// shortened, and returns payload.ErrAlertTooLarge if the payload does not fit
+// even with the body shortened to payload.Ellipsis.
+func (p *Payload) TruncateAlertBody(maxBytes int) (bool, error) {
+	total, err := p.EstimatedSize()
+	if err != nil {
+		return false, err
+	}
+	if total <= maxBytes {
+		return false, nil
+	}
 
+	var alert payload.Alert
+	switch a := p.APS.Alert.(type) {
+	case *payload.Alert:
+		alert = *a
+	case payload.Alert:
+		alert = a
+	case string:
+		alert = payload.Alert{Body: a}
+	default:
+		return false, fmt.Errorf("cannot truncate an alert of type %T", p.APS.Alert)
+	}
+
+	// The alert must lose what the payload exceeds maxBytes by. A string alert
+	// is encoded like the body of a dictionary, so measuring it as one only
+	// adds the constant size of the "body" key to both sides.
+	dict, err := alert.MarshalJSONFast()
+	if err != nil {
+		return false, err
+	}
+	truncated, err := payload.TruncateBody(&alert, len(dict)-(total-maxBytes))
+	if err != nil || !truncated {
+		return false, err
+	}
+	switch p.APS.Alert.(type) {
+	case *payload.Alert:
+		p.APS.Alert = &alert
+	case payload.Alert:
+		p.APS.Alert = alert
+	case string:
+		p.APS.Alert = alert.Body
+	}
+	return true, nil
+}
+
+// appendJSONFast appends the JSON encoding of the payload to b.
+func (p Payload) appendJSONFast(b []byte) ([]byte, error) {
+	var err error
+	b = append(b, `{"aps":`...)
+	// --- 1. aps ---
+	b, err = p.APS.AppendJSONFast(b)
+	if err != nil {
+		return nil, err
+	}
 	// --- 2. CustomData ---
 	if len(p.CustomData) > 0 {
 		b = append(b, ',')
-		b = append(b, customDataBytes...)
+		b, err = marshalCustomData(b, p.CustomData)
+		if err != nil {
+			return nil, err
+		}
 	}
 	b = append(b, '}')
-
 	return b, nil
 }
 
@@ -2,11 +2,14 @@ package apns_test
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/takimoto3/apns"
+	"github.com/takimoto3/apns/notification"
 	"github.com/takimoto3/apns/payload"
 )
 
@@ -151,3 +154,176 @@ func TestPayloadMarshalJSONTo3(t *testing.T) {
 		})
 	}
 }
+
+func TestPayload_MarshalJSONFast_HTMLEscape(t *testing.T) {
+	// Custom data is encoded with payload.EncodeValue, which escapes HTML
+	// characters like encoding/json.
+	p := apns.Payload{
+		APS:        payload.APS{Alert: "hello"},
+		CustomData: map[string]any{"link": "https://example.com/?a=1&b=<2>"},
+	}
+	fast, err := p.MarshalJSONFast()
+	if err != nil {
+		t.Fatalf("MarshalJSONFast() unexpected error: %v", err)
+	}
+	std, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	if string(fast) != string(std) {
+		t.Errorf("MarshalJSONFast() = %s, json.Marshal() = %s", fast, std)
+	}
+	if strings.ContainsAny(string(fast), "<>&") {
+		t.Errorf("MarshalJSONFast() = %s, want <, > and & escaped", fast)
+	}
+}
+
+func TestPayload_EstimatedSize(t *testing.T) {
+	tests := map[string]apns.Payload{
+		"aps only": {
+			APS: payload.APS{Alert: payload.Alert{Title: "Hello", Body: "World"}, Badge: 3},
+		},
+		"with custom data": {
+			APS: payload.APS{Alert: "hi", Sound: "default"},
+			CustomData: map[string]any{
+				"user_id": 42,
+				"tags":    []string{"a", "b"},
+				"meta":    map[string]any{"ok": true, "quote": `"x"`},
+			},
+		},
+		"live activity": {
+			APS: payload.APS{
+				Event:        "update",
+				ContentState: map[string]any{"status": strings.Repeat("s", 2000)},
+			},
+		},
+	}
+
+	for name, p := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := p.EstimatedSize()
+			if err != nil {
+				t.Fatalf("EstimatedSize failed: %v", err)
+			}
+			b, err := p.MarshalJSONFast()
+			if err != nil {
+				t.Fatalf("MarshalJSONFast failed: %v", err)
+			}
+			if got != len(b) {
+				t.Errorf("EstimatedSize() = %d, want %d", got, len(b))
+			}
+		})
+	}
+
+	bad := apns.Payload{CustomData: map[string]any{"ch": make(chan int)}}
+	if _, err := bad.EstimatedSize(); !errors.Is(err, payload.ErrInvalidType) {
+		t.Errorf("EstimatedSize() error = %v, want %v", err, payload.ErrInvalidType)
+	}
+}
+
+func TestPayload_EstimatedSize_HighWaterMark(t *testing.T) {
+	// Larger than any buffer the other tests use, so that only EstimatedSize
+	// can have recorded it.
+	p := apns.Payload{CustomData: map[string]any{"blob": strings.Repeat("x", 1<<21)}}
+	n, err := p.EstimatedSize()
+	if err != nil {
+		t.Fatalf("EstimatedSize() unexpected error: %v", err)
+	}
+	if got := payload.BufferHighWaterMark(); got < n {
+		t.Errorf("BufferHighWaterMark() = %d, want at least %d", got, n)
+	}
+}
+
+func TestPayload_Fits(t *testing.T) {
+	// {"aps":{"alert":""}} is 20 bytes; fill the alert up to the limits.
+	sized := func(n int) apns.Payload {
+		return apns.Payload{APS: payload.APS{Alert: strings.Repeat("a", n-20)}}
+	}
+
+	tests := map[string]struct {
+		payload  apns.Payload
+		pushType notification.PushType
+		want     bool
+	}{
+		"at limit":         {sized(apns.MaxPayloadSize), notification.Alert, true},
+		"over limit":       {sized(apns.MaxPayloadSize + 1), notification.Alert, false},
+		"voip at limit":    {sized(apns.MaxVoIPPayloadSize), notification.Voip, true},
+		"voip over limit":  {sized(apns.MaxVoIPPayloadSize + 1), notification.Voip, false},
+		"unsupported type": {apns.Payload{CustomData: map[string]any{"ch": make(chan int)}}, notification.Alert, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.payload.Fits(tt.pushType); got != tt.want {
+				t.Errorf("Fits(%s) = %v, want %v", tt.pushType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayload_TruncateAlertBody(t *testing.T) {
+	long := strings.Repeat("通知の本文です。", 200) // 24 bytes per repetition
+	tests := map[string]*apns.Payload{
+		"alert pointer": {
+			APS:        payload.APS{Alert: &payload.Alert{Title: "Title", Body: long}, Badge: 1},
+			CustomData: map[string]any{"id": "abc"},
+		},
+		"alert value": {
+			APS: payload.APS{Alert: payload.Alert{Body: long}, Sound: "default"},
+		},
+		"string alert": {
+			APS:        payload.APS{Alert: long},
+			CustomData: map[string]any{"meta": map[string]any{"k": "v"}},
+		},
+	}
+
+	for name, p := range tests {
+		t.Run(name, func(t *testing.T) {
+			original := p.Clone()
+			truncated, err := p.TruncateAlertBody(apns.MaxPayloadSize)
+			if err != nil {
+				t.Fatalf("TruncateAlertBody failed: %v", err)
+			}
+			if !truncated {
+				t.Fatal("Expected the body to be truncated")
+			}
+			b, err := p.MarshalJSONFast()
+			if err != nil {
+				t.Fatalf("MarshalJSONFast failed: %v", err)
+			}
+			if len(b) > apns.MaxPayloadSize {
+				t.Errorf("payload takes %d bytes, more than %d", len(b), apns.MaxPayloadSize)
+			}
+			if len(b) < apns.MaxPayloadSize-len("通") {
+				t.Errorf("payload takes %d bytes, expected the body to be cut at the last rune that fits", len(b))
+			}
+			var got apns.Payload
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("truncated payload is not valid JSON: %v", err)
+			}
+			body := got.APS.Alert
+			if a, ok := body.(*payload.Alert); ok {
+				body = a.Body
+			}
+			s := body.(string)
+			if !strings.HasSuffix(s, payload.Ellipsis) || !strings.HasPrefix(long, strings.TrimSuffix(s, payload.Ellipsis)) {
+				t.Errorf("unexpected truncated body %q", s)
+			}
+			if diff := cmp.Diff(original.CustomData, p.CustomData); diff != "" {
+				t.Errorf("CustomData changed (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	small := &apns.Payload{APS: payload.APS{Alert: "hi"}}
+	if truncated, err := small.TruncateAlertBody(apns.MaxPayloadSize); err != nil || truncated {
+		t.Errorf("TruncateAlertBody of a small payload = %t, %v; want false, nil", truncated, err)
+	}
+	noAlert := &apns.Payload{APS: payload.APS{ContentAvailable: 1}, CustomData: map[string]any{"blob": long}}
+	if _, err := noAlert.TruncateAlertBody(apns.MaxPayloadSize); err == nil {
+		t.Error("Expected an error for a payload without an alert")
+	}
+	tooLarge := &apns.Payload{APS: payload.APS{Alert: long}, CustomData: map[string]any{"blob": long + long}}
+	if _, err := tooLarge.TruncateAlertBody(apns.MaxPayloadSize); !errors.Is(err, payload.ErrAlertTooLarge) {
+		t.Errorf("TruncateAlertBody error = %v, want %v", err, payload.ErrAlertTooLarge)
+	}
+}
@@ -1,7 +1,9 @@
 package apns_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -278,3 +280,192 @@ func TestPayloadMarshalJSONTo3_Realistic(t *testing.T) {
 		})
 	}
 }
+
+func TestPayload_Marshal(t *testing.T) {
+	tests := map[string]*apns.Payload{
+		"aps only": {
+			APS: payload.APS{Alert: payload.Alert{Title: "Hello", Body: "World"}, Badge: 3},
+		},
+		"with custom data": {
+			APS: payload.APS{Alert: "hi", Sound: "default"},
+			CustomData: map[string]any{
+				"user_id": 42,
+				"tags":    []string{"a", "b"},
+				"meta":    map[string]any{"ok": true},
+			},
+		},
+		"background": {
+			APS:        payload.APS{ContentAvailable: 1},
+			CustomData: map[string]any{"sync": "full"},
+		},
+	}
+
+	for name, p := range tests {
+		t.Run(name, func(t *testing.T) {
+			fast, err := p.Marshal(true)
+			if err != nil {
+				t.Fatalf("Marshal(true) failed: %v", err)
+			}
+			std, err := p.Marshal(false)
+			if err != nil {
+				t.Fatalf("Marshal(false) failed: %v", err)
+			}
+			if diff := cmp.Diff(std, fast, JSONComparer); diff != "" {
+				t.Errorf("fast and standard encodings differ (-std +fast):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPayload_MarshalIndent(t *testing.T) {
+	p := &apns.Payload{
+		APS: payload.APS{Alert: payload.Alert{Title: "Hello", Body: "World"}, Badge: 3},
+		CustomData: map[string]any{
+			"user_id": 42,
+			"meta":    map[string]any{"ok": true},
+		},
+	}
+
+	indented, err := p.MarshalIndent()
+	if err != nil {
+		t.Fatalf("MarshalIndent() failed: %v", err)
+	}
+	compact, err := p.MarshalJSONFast()
+	if err != nil {
+		t.Fatalf("MarshalJSONFast() failed: %v", err)
+	}
+	if !bytes.Contains(indented, []byte("\n  \"aps\": {")) {
+		t.Errorf("MarshalIndent() is not indented:\n%s", indented)
+	}
+	if diff := cmp.Diff(compact, indented, JSONComparer); diff != "" {
+		t.Errorf("indented and compact encodings differ (-compact +indented):\n%s", diff)
+	}
+}
+
+func TestPayload_SetCustom(t *testing.T) {
+	p := &apns.Payload{APS: payload.APS{Alert: "hi"}}
+	if err := p.SetCustom("user_id", 42); err != nil {
+		t.Fatalf("SetCustom failed: %v", err)
+	}
+	if err := p.SetCustom("user_id", 43); err != nil {
+		t.Fatalf("SetCustom failed: %v", err)
+	}
+	if err := p.SetCustom("aps", map[string]any{"alert": "clobbered"}); !errors.Is(err, apns.ErrReservedCustomKey) {
+		t.Errorf("SetCustom(\"aps\") error = %v, want %v", err, apns.ErrReservedCustomKey)
+	}
+	if diff := cmp.Diff(map[string]any{"user_id": 43}, p.CustomData); diff != "" {
+		t.Errorf("CustomData mismatch (-want +got):\n%s", diff)
+	}
+	if p.APS.Alert != "hi" {
+		t.Errorf("APS.Alert = %v, want hi", p.APS.Alert)
+	}
+}
+
+func TestPayload_MergeCustom(t *testing.T) {
+	p := &apns.Payload{APS: payload.APS{Alert: "hi"}}
+	if err := p.MergeCustom(nil); err != nil || p.CustomData != nil {
+		t.Fatalf("MergeCustom(nil) = %v, CustomData = %v; want no error and nil CustomData", err, p.CustomData)
+	}
+	base := map[string]any{"user_id": 42, "locale": "en"}
+	if err := p.MergeCustom(base); err != nil {
+		t.Fatalf("MergeCustom failed: %v", err)
+	}
+	if err := p.MergeCustom(map[string]any{"locale": "ja", "request_id": "r-1"}); err != nil {
+		t.Fatalf("MergeCustom failed: %v", err)
+	}
+	want := map[string]any{"user_id": 42, "locale": "ja", "request_id": "r-1"}
+	if diff := cmp.Diff(want, p.CustomData); diff != "" {
+		t.Errorf("CustomData mismatch (-want +got):\n%s", diff)
+	}
+	if base["locale"] != "en" {
+		t.Error("MergeCustom must not modify the merged map")
+	}
+
+	err := p.MergeCustom(map[string]any{"extra": true, "aps": map[string]any{}})
+	if !errors.Is(err, apns.ErrReservedCustomKey) {
+		t.Errorf("MergeCustom with \"aps\" error = %v, want %v", err, apns.ErrReservedCustomKey)
+	}
+	if diff := cmp.Diff(want, p.CustomData); diff != "" {
+		t.Errorf("CustomData changed by rejected merge (-want +got):\n%s", diff)
+	}
+}
+
+func TestPayload_UnmarshalJSON(t *testing.T) {
+	stale := notification.EpochTimeFromUnix(1700000000)
+	tests := map[string]*apns.Payload{
+		"aps only": {
+			APS: payload.APS{Alert: &payload.Alert{Title: "Hello", Body: "World"}, Badge: 3},
+		},
+		"string alert and sound with custom data": {
+			APS: payload.APS{Alert: "hi", Sound: "default", ThreadID: "thread-1"},
+			CustomData: map[string]any{
+				"user_id": 42,
+				"ratio":   0.5,
+				"tags":    []any{"a", "b"},
+				"meta":    map[string]any{"ok": true, "count": 2, "nested": map[string]any{"id": 7}},
+				"none":    nil,
+			},
+		},
+		"critical sound": {
+			APS: payload.APS{
+				Alert:          &payload.Alert{Body: "fire", LocArgs: []string{"a"}, LocKey: "FIRE"},
+				Sound:          &payload.Sound{Name: "alarm.caf", Critical: 1, Volume: 0.8},
+				MutableContent: 1,
+				RelevanceScore: 0.25,
+			},
+		},
+		"background": {
+			APS:        payload.APS{ContentAvailable: 1},
+			CustomData: map[string]any{"sync": "full"},
+		},
+		"live activity": {
+			APS: payload.APS{
+				Event:        "update",
+				Timestamp:    stale,
+				StaleDate:    stale,
+				ContentState: map[string]any{"score": 3, "team": "home", "progress": 0.75},
+			},
+		},
+	}
+
+	for name, p := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var got apns.Payload
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if diff := cmp.Diff(p, &got); diff != "" {
+				t.Errorf("round-tripped payload mismatch (-want +got):\n%s", diff)
+			}
+			again, err := json.Marshal(&got)
+			if err != nil {
+				t.Fatalf("Marshal of round-tripped payload failed: %v", err)
+			}
+			if diff := cmp.Diff(data, again, JSONComparer); diff != "" {
+				t.Errorf("re-marshaled JSON mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPayload_UnmarshalJSON_Errors(t *testing.T) {
+	tests := map[string]string{
+		"not an object":   `[1]`,
+		"numeric alert":   `{"aps":{"alert":1}}`,
+		"boolean sound":   `{"aps":{"sound":true}}`,
+		"array state":     `{"aps":{"content-state":[1]}}`,
+		"malformed field": `{"aps":{"thread-id":1}}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var p apns.Payload
+			if err := json.Unmarshal([]byte(data), &p); err == nil {
+				t.Errorf("Unmarshal(%s) succeeded, want error", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// pingDeviceToken is a device token APNs always rejects with BadDeviceToken.
+const pingDeviceToken = "ping"
+
+// Ping verifies the connection and credentials of the client without
+// notifying any device. It sends a background notification for PingTopic to
+// an invalid device token and returns nil if APNs rejects it with
+// BadDeviceToken, which it only does once the request is authenticated.
+// Authentication failures such as InvalidProviderToken, TLS and other
+// transport errors are returned as is. A token-based client must have a
+// PingTopic, otherwise Ping fails without sending a request.
+//
+// Pings are not counted in Stats and not reported to OnResult.
+func (cli *Client) Ping(ctx context.Context) error {
+	cli.freeze()
+	if cli.closed.Load() {
+		return ErrClientClosed
+	}
+	if cli.PingTopic == "" && cli.tokenProvider() != nil {
+		return errors.New("APNs ping: PingTopic is required with token-based authentication")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cli.inner.Host+Path+pingDeviceToken, strings.NewReader(`{"aps":{"content-available":1}}`))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("apns-push-type", "background")
+	req.Header.Set("apns-priority", "5")
+	if cli.PingTopic != "" {
+		req.Header.Set("apns-topic", cli.PingTopic)
+	}
+	if cli.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cli.RequestTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	cli.logRequest(req)
+	_, _, err = cli.exchange(req)
+	if err == nil {
+		return errors.New("APNs ping: unexpected success for an invalid device token")
+	}
+	if errors.Is(err, ErrBadDeviceToken) {
+		return nil
+	}
+	return fmt.Errorf("APNs ping failed: %w", err)
+}
@@ -0,0 +1,104 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Ping(t *testing.T) {
+	transportErr := errors.New("tls: handshake failure")
+
+	testCases := map[string]struct {
+		status      int
+		body        string
+		err         error
+		wantErr     bool
+		wantErrIs   error
+		errContains string
+	}{
+		"BadDeviceToken means authenticated": {
+			status: http.StatusBadRequest,
+			body:   `{"reason":"BadDeviceToken"}`,
+		},
+		"InvalidProviderToken": {
+			status:      http.StatusForbidden,
+			body:        `{"reason":"InvalidProviderToken"}`,
+			wantErr:     true,
+			errContains: "InvalidProviderToken",
+		},
+		"Transport error": {
+			err:       transportErr,
+			wantErr:   true,
+			wantErrIs: transportErr,
+		},
+		"Unexpected success": {
+			status:      http.StatusOK,
+			wantErr:     true,
+			errContains: "unexpected success",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var gotReq *http.Request
+			transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				gotReq = r
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return newResponse(tc.status, tc.body), nil
+			})
+			client := newTestClient(t, transport)
+
+			client.PingTopic = "com.example.app"
+			client.OnResult = func(string, int, string, time.Duration) {
+				t.Error("OnResult called for a ping")
+			}
+
+			err := client.Ping(context.Background())
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("Ping failed: %v", err)
+				}
+			} else {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				if tc.wantErrIs != nil && !errors.Is(err, tc.wantErrIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.wantErrIs, err)
+				}
+				if tc.errContains != "" && !strings.Contains(err.Error(), tc.errContains) {
+					t.Errorf("Expected error to contain %q, got %v", tc.errContains, err)
+				}
+			}
+
+			if stats := client.Stats(); stats != (Stats{}) {
+				t.Errorf("Expected pings not to be counted, got %+v", stats)
+			}
+			if got := gotReq.Header.Get("apns-topic"); got != "com.example.app" {
+				t.Errorf("Expected apns-topic com.example.app, got %q", got)
+			}
+			if got := gotReq.Header.Get("apns-push-type"); got != "background" {
+				t.Errorf("Expected apns-push-type background, got %q", got)
+			}
+			if !strings.HasSuffix(gotReq.URL.Path, Path+pingDeviceToken) {
+				t.Errorf("Unexpected path %s", gotReq.URL.Path)
+			}
+		})
+	}
+}
+
+func TestClient_Ping_TopicRequired(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Error("Ping sent a request without a topic")
+		return newResponse(http.StatusBadRequest, `{"reason":"BadDeviceToken"}`), nil
+	})
+	client := newTestClient(t, transport)
+	if err := client.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "PingTopic is required") {
+		t.Errorf("Ping() error = %v, want a PingTopic is required error", err)
+	}
+}
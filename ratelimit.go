@@ -0,0 +1,22 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import "context"
+
+// Limiter paces the requests sent by PushMulti, PushChunked and PushBatch.
+// *rate.Limiter from golang.org/x/time/rate satisfies it.
+type Limiter interface {
+	// Wait blocks until a request may be sent, or returns an error if ctx is
+	// done first or the request can never be sent.
+	Wait(ctx context.Context) error
+}
+
+// sendPaced waits for the client's Limiter, if any, and sends the notification.
+func (cli *Client) sendPaced(ctx context.Context, n *Notification, body []byte) (*Response, error) {
+	if cli.Limiter != nil {
+		if err := cli.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return cli.send(ctx, n, body)
+}
@@ -0,0 +1,113 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+// intervalLimiter is a Limiter that lets one request through per interval.
+type intervalLimiter struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+}
+
+func (l *intervalLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	at := time.Now()
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestClient_Limiter(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	const interval = 20 * time.Millisecond
+	client.Limiter = &intervalLimiter{interval: interval}
+
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	tokens := make([]string, 6)
+	items := make([]BatchItem, len(tokens))
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token-%d", i)
+		items[i] = BatchItem{Token: tokens[i], Notification: n}
+	}
+	minDuration := time.Duration(len(tokens)-1) * interval
+
+	start := time.Now()
+	if _, err := client.PushMulti(context.Background(), n, tokens); err != nil {
+		t.Fatalf("PushMulti failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < minDuration {
+		t.Errorf("PushMulti: expected at least %v, took %v", minDuration, elapsed)
+	}
+
+	start = time.Now()
+	if _, err := client.PushBatch(context.Background(), items); err != nil {
+		t.Fatalf("PushBatch failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < minDuration {
+		t.Errorf("PushBatch: expected at least %v, took %v", minDuration, elapsed)
+	}
+	if got := hits.Load(); got != int32(2*len(tokens)) {
+		t.Errorf("Expected %d requests, got %d", 2*len(tokens), got)
+	}
+}
+
+func TestClient_Limiter_ContextCanceled(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	client.Limiter = &intervalLimiter{interval: time.Hour}
+
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	responses, err := client.PushMulti(ctx, n, []string{"token-a", "token-b", "token-c"})
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || multiErr.Len() != 2 {
+		t.Fatalf("Expected a *MultiError with 2 failures, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the failures to be context.DeadlineExceeded, got %v", multiErr.Failures)
+	}
+	if len(responses) != 1 || hits.Load() != 1 {
+		t.Errorf("Expected only the first request to be sent, got %d responses and %d requests", len(responses), hits.Load())
+	}
+}
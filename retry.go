@@ -0,0 +1,86 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRetryBackoff is the delay before the first retry if Client.RetryBackoff is zero.
+const DefaultRetryBackoff = 100 * time.Millisecond
+
+// retryBudget caps the total number of retries of a batch operation.
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+// take consumes one retry from the budget and reports whether one was available.
+func (b *retryBudget) take() bool {
+	return b.remaining.Add(-1) >= 0
+}
+
+type retryBudgetKey struct{}
+
+// withRetryBudget returns a context carrying a retry budget for a batch operation,
+// unless the client has no budget configured or ctx already carries one.
+func (cli *Client) withRetryBudget(ctx context.Context) context.Context {
+	if cli.MaxRetries <= 0 || cli.RetryBudget <= 0 {
+		return ctx
+	}
+	if _, ok := ctx.Value(retryBudgetKey{}).(*retryBudget); ok {
+		return ctx
+	}
+	b := &retryBudget{}
+	b.remaining.Store(int64(cli.RetryBudget))
+	return context.WithValue(ctx, retryBudgetKey{}, b)
+}
+
+// retryable reports whether a failed send may succeed if sent again:
+// transport errors and APNs responses with status 429, 500 or 503.
+func retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, ErrHTTP2Required) {
+		return false
+	}
+	var apnsErr *Error
+	if errors.As(err, &apnsErr) {
+		switch apnsErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+			return true
+		}
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// sendWithRetry calls send and retries it with exponential backoff while the
+// error is retryable, up to MaxRetries times and within the retry budget of ctx.
+func (cli *Client) sendWithRetry(ctx context.Context, send func() (*Response, error)) (*Response, error) {
+	response, err := send()
+	if cli.MaxRetries <= 0 {
+		return response, err
+	}
+	budget, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	backoff := cli.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for attempt := 0; attempt < cli.MaxRetries && retryable(ctx, err); attempt++ {
+		if budget != nil && !budget.take() {
+			break
+		}
+		timer := time.NewTimer(backoff << attempt)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, err
+		case <-timer.C:
+		}
+		response, err = send()
+	}
+	return response, err
+}
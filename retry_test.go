@@ -0,0 +1,95 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func unavailableResponse() *http.Response {
+	return newResponse(http.StatusServiceUnavailable, `{"reason":"ServiceUnavailable"}`)
+}
+
+func TestClient_Push_MaxRetries(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if hits.Add(1) < 3 {
+			return unavailableResponse(), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+	client.MaxRetries = 3
+	client.RetryBackoff = time.Millisecond
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+
+	// Non-retryable errors are returned immediately.
+	hits.Store(0)
+	client.inner.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		return newResponse(http.StatusBadRequest, `{"reason":"BadDeviceToken"}`), nil
+	})
+	if _, err := client.Push(context.Background(), n); err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt for a non-retryable error, got %d", got)
+	}
+}
+
+func TestClient_PushMulti_RetryBudget(t *testing.T) {
+	var hits atomic.Int32
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		if strings.HasSuffix(r.URL.Path, "/token-0") {
+			return newResponse(http.StatusOK, ""), nil
+		}
+		return unavailableResponse(), nil
+	})
+	client := newTestClient(t, transport)
+	client.MaxRetries = 3
+	client.RetryBackoff = time.Millisecond
+	client.RetryBudget = 5
+
+	const failing = 20
+	tokens := make([]string, failing+1)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token-%d", i)
+	}
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	_, err := client.PushMulti(context.Background(), n, tokens)
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || len(multiErr.Failures) != failing {
+		t.Fatalf("Expected %d failures, got %v", failing, err)
+	}
+
+	// One request per token plus at most RetryBudget retries in total,
+	// instead of MaxRetries per failing token.
+	if got, want := int(hits.Load()), len(tokens)+client.RetryBudget; got != want {
+		t.Errorf("Expected %d requests, got %d", want, got)
+	}
+}
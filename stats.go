@@ -0,0 +1,60 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import "sync"
+
+// Stats holds counters for the requests a Client has sent to APNs. Requests
+// sent by Ping are not counted.
+type Stats struct {
+	// Sent is the number of requests sent to APNs, successful or not.
+	Sent uint64
+	// Succeeded is the number of requests accepted by APNs.
+	Succeeded uint64
+	// Failed is the number of requests that failed, either with an APNs error
+	// response or a transport error.
+	Failed uint64
+}
+
+// stats guards the counters of a Client so that snapshots and resets are
+// consistent with concurrent sends.
+type stats struct {
+	mu sync.Mutex
+	s  Stats
+}
+
+func (st *stats) record(err error) {
+	st.mu.Lock()
+	st.s.Sent++
+	if err != nil {
+		st.s.Failed++
+	} else {
+		st.s.Succeeded++
+	}
+	st.mu.Unlock()
+}
+
+func (st *stats) snapshot() Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.s
+}
+
+func (st *stats) reset() Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s := st.s
+	st.s = Stats{}
+	return s
+}
+
+// Stats returns a snapshot of the client's request counters.
+func (cli *Client) Stats() Stats {
+	return cli.stats.snapshot()
+}
+
+// ResetStats atomically returns the current counters and sets them to zero.
+// Every request is counted in exactly one snapshot, which makes it suitable for
+// exporting metrics at fixed intervals without double counting.
+func (cli *Client) ResetStats() Stats {
+	return cli.stats.reset()
+}
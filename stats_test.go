@@ -0,0 +1,160 @@
+package apns
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestClient_Stats(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, "bad-token") {
+			return newResponse(http.StatusBadRequest, `{"reason":"BadDeviceToken"}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID: "com.example.app",
+		Type:     notification.Alert,
+		Payload:  &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	for _, token := range []string{"good-token", "good-token", "bad-token"} {
+		n.DeviceToken = token
+		_, _ = client.Push(context.Background(), n)
+	}
+
+	want := Stats{Sent: 3, Succeeded: 2, Failed: 1}
+	if got := client.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got := client.ResetStats(); got != want {
+		t.Errorf("ResetStats() = %+v, want %+v", got, want)
+	}
+	if got := client.Stats(); got != (Stats{}) {
+		t.Errorf("Stats() after reset = %+v, want zero", got)
+	}
+}
+
+func TestClient_ResetStats_Concurrent(t *testing.T) {
+	transport := respondWith(http.StatusOK, "")
+	client := newTestClient(t, transport)
+
+	const workers = 8
+	const pushesPerWorker = 200
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := &Notification{
+				BundleID:    "com.example.app",
+				DeviceToken: "test-device-token",
+				Type:        notification.Alert,
+				Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+			}
+			for j := 0; j < pushesPerWorker; j++ {
+				if _, err := client.Push(context.Background(), n); err != nil {
+					t.Errorf("Push failed: %v", err)
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var total Stats
+	go func() {
+		defer close(done)
+		for {
+			s := client.ResetStats()
+			if s.Sent != s.Succeeded+s.Failed {
+				t.Errorf("inconsistent snapshot: %+v", s)
+			}
+			total.Sent += s.Sent
+			total.Succeeded += s.Succeeded
+			total.Failed += s.Failed
+			if total.Sent == workers*pushesPerWorker {
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+	s := client.ResetStats()
+	total.Sent += s.Sent
+	total.Succeeded += s.Succeeded
+
+	if total.Sent != workers*pushesPerWorker {
+		t.Errorf("total sent = %d, want %d", total.Sent, workers*pushesPerWorker)
+	}
+	if total.Succeeded != workers*pushesPerWorker {
+		t.Errorf("total succeeded = %d, want %d", total.Succeeded, workers*pushesPerWorker)
+	}
+}
+
+func TestClient_OnResult(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		time.Sleep(time.Millisecond)
+		if strings.HasSuffix(r.URL.Path, "bad-token") {
+			return newResponse(http.StatusGone, `{"reason":"Unregistered","timestamp":1700000000000}`), nil
+		}
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	type result struct {
+		pushType string
+		status   int
+		reason   string
+		latency  time.Duration
+	}
+	var results []result
+	client.OnResult = func(pushType string, statusCode int, reason string, latency time.Duration) {
+		results = append(results, result{pushType, statusCode, reason, latency})
+	}
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "good-token",
+		Type:        notification.Background,
+		Payload:     &Payload{APS: payload.APS{ContentAvailable: 1}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	n.DeviceToken = "bad-token"
+	if _, err := client.Push(context.Background(), n); err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if r := results[0]; r.pushType != "background" || r.status != http.StatusOK || r.reason != "" {
+		t.Errorf("Unexpected success result: %+v", r)
+	}
+	if r := results[1]; r.pushType != "background" || r.status != http.StatusGone || r.reason != "Unregistered" {
+		t.Errorf("Unexpected failure result: %+v", r)
+	}
+	for i, r := range results {
+		if r.latency <= 0 {
+			t.Errorf("result %d: expected a positive latency, got %v", i, r.latency)
+		}
+	}
+
+	// A panicking callback must not break sending.
+	client.OnResult = func(string, int, string, time.Duration) { panic("boom") }
+	n.DeviceToken = "good-token"
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Errorf("Push failed with a panicking OnResult: %v", err)
+	}
+}
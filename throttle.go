@@ -0,0 +1,66 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/notification/priority"
+)
+
+// throttle counts consecutive 429 TooManyRequests responses for the adaptive
+// priority downgrade controlled by Client.DowngradeAfterThrottles.
+type throttle struct {
+	consecutive atomic.Int64
+}
+
+// record updates the count with the outcome of a request. Errors other than
+// 429 leave it unchanged, since they say nothing about throttling.
+func (t *throttle) record(err error) {
+	if err == nil {
+		t.consecutive.Store(0)
+		return
+	}
+	var apnsErr *Error
+	if errors.As(err, &apnsErr) && apnsErr.StatusCode == http.StatusTooManyRequests {
+		t.consecutive.Add(1)
+	}
+}
+
+// downgradable reports whether the notification may be sent with
+// priority.Conserve instead of priority.Immediate without changing how it is
+// handled by the device: a silent, content-available only notification of a
+// push type that allows a Conserve priority. Notifications that alert the user
+// keep their priority, and so do those without a Payload, e.g. sent by PushRaw,
+// whose content is unknown.
+func downgradable(n *Notification) bool {
+	switch n.Type {
+	case notification.Alert, notification.Background, notification.Liveactivity, notification.Widgets:
+	default:
+		return false
+	}
+	if n.Payload == nil {
+		return false
+	}
+	aps := &n.Payload.APS
+	return aps.ContentAvailable != nil && aps.Alert == nil && aps.Badge == nil && aps.Sound == nil
+}
+
+// applyThrottle returns the notification to send, lowering its priority to
+// priority.Conserve while the client is being throttled.
+func (cli *Client) applyThrottle(n *Notification) *Notification {
+	if cli.DowngradeAfterThrottles <= 0 || !downgradable(n) {
+		return n
+	}
+	if n.Priority != priority.None && n.Priority != priority.Immediate {
+		return n
+	}
+	if cli.throttle.consecutive.Load() < int64(cli.DowngradeAfterThrottles) {
+		return n
+	}
+	c := *n
+	c.Priority = priority.Conserve
+	return &c
+}
@@ -0,0 +1,87 @@
+package apns
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/notification/priority"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestClient_Push_DowngradeAfterThrottles(t *testing.T) {
+	statuses := []int{429, 429, 429, 200, 200}
+	var priorities []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		status := statuses[len(priorities)]
+		priorities = append(priorities, r.Header.Get("apns-priority"))
+		body := ""
+		if status == http.StatusTooManyRequests {
+			body = `{"reason":"TooManyRequests"}`
+		}
+		return newResponse(status, body), nil
+	})
+	client := newTestClient(t, transport)
+	client.DowngradeAfterThrottles = 2
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Priority:    priority.Immediate,
+		Payload:     &Payload{APS: payload.APS{ContentAvailable: 1}},
+	}
+	for range statuses {
+		_, _ = client.Push(context.Background(), n)
+	}
+
+	want := []string{"10", "10", "5", "5", "10"}
+	if strings.Join(priorities, ",") != strings.Join(want, ",") {
+		t.Errorf("apns-priority headers = %v, want %v", priorities, want)
+	}
+	if n.Priority != priority.Immediate {
+		t.Errorf("Expected the caller's notification to be unchanged, got priority %d", n.Priority)
+	}
+}
+
+func TestClient_Push_DowngradeAfterThrottles_Kept(t *testing.T) {
+	tests := map[string]*Notification{
+		"voip": {
+			Type:    notification.Voip,
+			Payload: &Payload{APS: payload.APS{Alert: "call"}},
+		},
+		"alert": {
+			Type:    notification.Alert,
+			Payload: &Payload{APS: payload.APS{Alert: "test"}},
+		},
+		"alert with content-available": {
+			Type:    notification.Alert,
+			Payload: &Payload{APS: payload.APS{Alert: "test", ContentAvailable: 1}},
+		},
+	}
+	for name, n := range tests {
+		t.Run(name, func(t *testing.T) {
+			var priorities []string
+			transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				priorities = append(priorities, r.Header.Get("apns-priority"))
+				return newResponse(http.StatusTooManyRequests, `{"reason":"TooManyRequests"}`), nil
+			})
+			client := newTestClient(t, transport)
+			client.DowngradeAfterThrottles = 1
+			client.AutoPriority = true
+
+			n.BundleID = "com.example.app"
+			n.DeviceToken = "test-device-token"
+			for i := 0; i < 3; i++ {
+				_, _ = client.Push(context.Background(), n)
+			}
+			for i, p := range priorities {
+				if p != "10" {
+					t.Errorf("request %d: apns-priority = %s, want 10", i, p)
+				}
+			}
+		})
+	}
+}
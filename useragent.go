@@ -0,0 +1,74 @@
+// package apns provides a client for sending notifications to the Apple Push Notification service.
+package apns
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+const modulePath = "github.com/takimoto3/apns"
+
+// DefaultUserAgent returns the User-Agent sent when Client.UserAgent is empty,
+// e.g. "takimoto3-apns/v1.2.3 (github.com/takimoto3/apns; go1.24.11)". The
+// module version is read from the build info of the running binary and is
+// "devel" when it is unavailable.
+func DefaultUserAgent() string {
+	return defaultUserAgent()
+}
+
+var defaultUserAgent = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	return userAgent(info, ok)
+})
+
+// userAgent builds the default User-Agent from the build info.
+func userAgent(info *debug.BuildInfo, ok bool) string {
+	version := "devel"
+	if ok {
+		if v := moduleVersion(info); v != "" && v != "(devel)" {
+			version = v
+		}
+	}
+	return "takimoto3-apns/" + version + " (" + modulePath + "; " + runtime.Version() + ")"
+}
+
+// moduleVersion returns the version of this module in the build info, whether
+// it is the main module or a dependency.
+func moduleVersion(info *debug.BuildInfo) string {
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return ""
+}
+
+// WithUserAgent returns an option that sets the User-Agent sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(cli *Client) error {
+		cli.UserAgent = ua
+		return nil
+	}
+}
+
+// setUserAgent sets the User-Agent of the request unless it already has one,
+// e.g. from Notification.Headers.
+func (cli *Client) setUserAgent(req *http.Request) {
+	if req.Header.Get("User-Agent") != "" {
+		return
+	}
+	ua := cli.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent()
+	}
+	req.Header.Set("User-Agent", ua)
+}
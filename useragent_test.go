@@ -0,0 +1,111 @@
+package apns
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/takimoto3/apns/notification"
+	"github.com/takimoto3/apns/payload"
+)
+
+func TestDefaultUserAgent(t *testing.T) {
+	ua := DefaultUserAgent()
+	if !strings.Contains(ua, modulePath) {
+		t.Errorf("DefaultUserAgent() = %q, want it to contain %q", ua, modulePath)
+	}
+	if !strings.Contains(ua, runtime.Version()) {
+		t.Errorf("DefaultUserAgent() = %q, want it to contain %q", ua, runtime.Version())
+	}
+	if !regexp.MustCompile(`^takimoto3-apns/(v\d+\.\d+\.\d+\S*|devel) \(`).MatchString(ua) {
+		t.Errorf("DefaultUserAgent() = %q, want a version-like token after the product name", ua)
+	}
+}
+
+func TestUserAgent_BuildInfo(t *testing.T) {
+	goVersion := runtime.Version()
+	tests := map[string]struct {
+		info *debug.BuildInfo
+		ok   bool
+		want string
+	}{
+		"no build info": {
+			want: "takimoto3-apns/devel (github.com/takimoto3/apns; " + goVersion + ")",
+		},
+		"main module in development": {
+			info: &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}},
+			ok:   true,
+			want: "takimoto3-apns/devel (github.com/takimoto3/apns; " + goVersion + ")",
+		},
+		"dependency": {
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.3"}},
+			},
+			ok:   true,
+			want: "takimoto3-apns/v1.2.3 (github.com/takimoto3/apns; " + goVersion + ")",
+		},
+		"replaced dependency": {
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/app"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.2.3", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.2.4"}}},
+			},
+			ok:   true,
+			want: "takimoto3-apns/v1.2.4 (github.com/takimoto3/apns; " + goVersion + ")",
+		},
+		"not a dependency": {
+			info: &debug.BuildInfo{Main: debug.Module{Path: "example.com/app"}},
+			ok:   true,
+			want: "takimoto3-apns/devel (github.com/takimoto3/apns; " + goVersion + ")",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := userAgent(tt.info, tt.ok); got != tt.want {
+				t.Errorf("userAgent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_UserAgent(t *testing.T) {
+	var userAgents []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		return newResponse(http.StatusOK, ""), nil
+	})
+	client := newTestClient(t, transport)
+
+	n := &Notification{
+		BundleID:    "com.example.app",
+		DeviceToken: "test-device-token",
+		Type:        notification.Alert,
+		Payload:     &Payload{APS: payload.APS{Alert: "test"}},
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	client = newTestClient(t, transport)
+	if err := client.Configure(WithUserAgent("my-service/2.0")); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+	n.Headers = map[string]string{"User-Agent": "per-notification/1.0"}
+	if _, err := client.Push(context.Background(), n); err != nil {
+		t.Fatalf("Client.Push failed: %v", err)
+	}
+
+	want := []string{DefaultUserAgent(), "my-service/2.0", "per-notification/1.0"}
+	for i := range want {
+		if userAgents[i] != want[i] {
+			t.Errorf("request %d: User-Agent = %q, want %q", i, userAgents[i], want[i])
+		}
+	}
+}